@@ -8,9 +8,32 @@ type Dropbox struct {
 	AppSecret string `mapstructure:"app-secret"`
 	RefreshToken string `mapstructure:"refresh-token"`
 	Configure bool `mapstructure:"configure"`
+	ConfigureHeadless bool `mapstructure:"configure-headless"`
+	ConfigureRedirectPort int `mapstructure:"configure-redirect-port"`
+	ConfigurePrintRefreshToken bool `mapstructure:"configure-print-refresh-token"`
+	CredentialsFile string `mapstructure:"credentials-file"`
+	CredentialsPassphrase string `mapstructure:"credentials-passphrase"`
 	Oauth2Token string `mapstructure:"oauth2-token"`
 	BaseUrl string `mapstructure:"base-url"`
 	SyncUserLastLogin bool `mapstructure:"sync-user-last-login"`
+	SyncResourceChangeEvents bool `mapstructure:"sync-resource-change-events"`
+	SyncGrantEvents bool `mapstructure:"sync-grant-events"`
+	SyncFolderActivity bool `mapstructure:"sync-folder-activity"`
+	AggregateLoginEvents bool `mapstructure:"aggregate-login-events"`
+	LoginAggregationHours int `mapstructure:"login-aggregation-hours"`
+	SyncSignInInsights bool `mapstructure:"sync-sign-in-insights"`
+	SignInInsightDays int `mapstructure:"sign-in-insight-days"`
+	SsoRequired bool `mapstructure:"sso-required"`
+	SyncTwoStepVerification bool `mapstructure:"sync-two-step-verification"`
+	SyncTeamPolicies bool `mapstructure:"sync-team-policies"`
+	TeamPolicyInsightDays int `mapstructure:"team-policy-insight-days"`
+	SyncDeviceActivity bool `mapstructure:"sync-device-activity"`
+	StaleInviteDays int `mapstructure:"stale-invite-days"`
+	ReadRequestsPerMinute int `mapstructure:"read-requests-per-minute"`
+	WriteRequestsPerMinute int `mapstructure:"write-requests-per-minute"`
+	TeamLogRequestsPerMinute int `mapstructure:"team-log-requests-per-minute"`
+	PageSize int `mapstructure:"page-size"`
+	AdaptivePageSize bool `mapstructure:"adaptive-page-size"`
 }
 
 func (c *Dropbox) findFieldByTag(tagValue string) (any, bool) {
@@ -15,8 +15,8 @@ var (
 		"app-secret",
 		field.WithDisplayName("App secret"),
 		field.WithIsSecret(true),
-		field.WithDescription("The app secret used to authenticate with Dropbox"),
-		field.WithRequired(true),
+		field.WithDescription("The app secret used to authenticate with Dropbox. Optional for refresh tokens obtained with --configure, which uses PKCE"),
+		field.WithRequired(false),
 	)
 	RefreshTokenField = field.StringField(
 		"refresh-token",
@@ -33,6 +33,46 @@ var (
 		field.WithRequired(false),
 		field.WithExportTarget(field.ExportTargetCLIOnly),
 	)
+	ConfigureHeadlessField = field.BoolField(
+		"configure-headless",
+		field.WithDisplayName("Configure without a redirect"),
+		field.WithDescription("With --configure, paste the authorization code shown by Dropbox instead of receiving it on a local redirect URI. "+
+			"Use on hosts where the browser can't reach localhost."),
+		field.WithRequired(false),
+		field.WithExportTarget(field.ExportTargetCLIOnly),
+	)
+	ConfigureRedirectPortField = field.IntField(
+		"configure-redirect-port",
+		field.WithDisplayName("Configure redirect port"),
+		field.WithDescription("With --configure, the local port receiving the OAuth redirect. "+
+			"http://127.0.0.1:<port>/oauth2/callback must be registered as a redirect URI on the Dropbox app."),
+		field.WithDefaultValue(53682),
+		field.WithInt(func(r *field.IntRuler) { r.Gte(1).Lte(65535) }),
+		field.WithExportTarget(field.ExportTargetCLIOnly),
+	)
+	ConfigurePrintRefreshTokenField = field.BoolField(
+		"configure-print-refresh-token",
+		field.WithDisplayName("Print the refresh token"),
+		field.WithDescription("With --configure, write the refresh token to stdout instead of to --credentials-file."),
+		field.WithRequired(false),
+		field.WithExportTarget(field.ExportTargetCLIOnly),
+	)
+	CredentialsFileField = field.StringField(
+		"credentials-file",
+		field.WithDisplayName("Credentials file"),
+		field.WithDescription("Path to an age-encrypted file holding the app key, app secret and refresh token. "+
+			"--configure writes it, and the connector reads credentials from it."),
+		field.WithRequired(false),
+		field.WithExportTarget(field.ExportTargetCLIOnly),
+	)
+	CredentialsPassphraseField = field.StringField(
+		"credentials-passphrase",
+		field.WithDisplayName("Credentials passphrase"),
+		field.WithIsSecret(true),
+		field.WithDescription("The passphrase used to encrypt and decrypt --credentials-file"),
+		field.WithRequired(false),
+		field.WithExportTarget(field.ExportTargetCLIOnly),
+	)
 
 	Oauth2TokenField = field.Oauth2Field(
 		"oauth2-token",
@@ -53,6 +93,142 @@ var (
 			"to be enabled on the Dropbox app, which requires re-authorizing the app."),
 		field.WithDefaultValue(false),
 	)
+	SyncResourceChangeEventsField = field.BoolField(
+		"sync-resource-change-events",
+		field.WithDisplayName("Sync resource change events"),
+		field.WithDescription("Emit resource change events for members, groups and team folders changed in Dropbox, derived from the team event log "+
+			"(team_log/get_events), so they can be refreshed between full syncs. Also syncs team folders and shared folders. "+
+			"Requires the \"Team event log\" (events.read) permission scope to be enabled on the Dropbox app, and team_data.member for team folders."),
+		field.WithDefaultValue(false),
+	)
+	SyncGrantEventsField = field.BoolField(
+		"sync-grant-events",
+		field.WithDisplayName("Sync grant events"),
+		field.WithDescription("Emit grant and revoke events for group memberships and admin roles changed in Dropbox, derived from the "+
+			"team event log (team_log/get_events), so access changed in the Dropbox console shows up before the next full sync. "+
+			"Requires the \"Team event log\" (events.read) permission scope to be enabled on the Dropbox app."),
+		field.WithDefaultValue(false),
+	)
+	SyncFolderActivityField = field.BoolField(
+		"sync-folder-activity",
+		field.WithDisplayName("Sync folder activity"),
+		field.WithDescription("Emit usage events for members working in team folders and shared folders, aggregated per member, "+
+			"folder and day, derived from the file_operations and sharing categories of the team event log (team_log/get_events). "+
+			"Also syncs the team folders and shared folders the events target. "+
+			"Requires the \"Team event log\" (events.read) and team_data.member permission scopes to be enabled on the Dropbox app."),
+		field.WithDefaultValue(false),
+	)
+	AggregateLoginEventsField = field.BoolField(
+		"aggregate-login-events",
+		field.WithDisplayName("Aggregate login events"),
+		field.WithDescription("With sync-user-last-login, emit only each member's newest sign-in per login aggregation window "+
+			"instead of one usage event per sign-in, once the window has closed."),
+		field.WithDefaultValue(false),
+	)
+	LoginAggregationHoursField = field.IntField(
+		"login-aggregation-hours",
+		field.WithDisplayName("Login aggregation window (hours)"),
+		field.WithDescription("Length of the windows sign-ins are aggregated over with aggregate-login-events. "+
+			"Set to 0 to aggregate within each page of the team event log instead."),
+		field.WithDefaultValue(24),
+		field.WithInt(func(r *field.IntRuler) { r.Gte(0) }),
+	)
+	SyncSignInInsightsField = field.BoolField(
+		"sync-sign-in-insights",
+		field.WithDisplayName("Sync sign-in insights"),
+		field.WithDescription("Sync security insights derived from the logins category of the team event log: repeated failed "+
+			"sign-ins, sign-ins from new countries, password sign-ins on SSO-required teams, admins signing in as members, and "+
+			"EMM and SSO errors. Requires the \"Team event log\" (events.read) permission scope to be enabled on the Dropbox app."),
+		field.WithDefaultValue(false),
+	)
+	SignInInsightDaysField = field.IntField(
+		"sign-in-insight-days",
+		field.WithDisplayName("Sign-in insight window (days)"),
+		field.WithDescription("Number of days of sign-ins sign-in insights are derived from."),
+		field.WithDefaultValue(7),
+		field.WithInt(func(r *field.IntRuler) { r.Gte(1) }),
+	)
+	SSORequiredField = field.BoolField(
+		"sso-required",
+		field.WithDisplayName("SSO required"),
+		field.WithDescription("The team requires SSO, so sign-in insights flag password sign-ins. Dropbox doesn't expose "+
+			"the team's SSO policy through its API."),
+		field.WithDefaultValue(false),
+	)
+	SyncTwoStepVerificationField = field.BoolField(
+		"sync-two-step-verification",
+		field.WithDisplayName("Sync two-step verification status"),
+		field.WithDescription("Add each member's two-step verification status and method, derived on every sync from the tfa "+
+			"events the team event log retains, to their profile. Requires the \"Team event log\" (events.read) permission scope to be enabled on the "+
+			"Dropbox app."),
+		field.WithDefaultValue(false),
+	)
+	SyncTeamPoliciesField = field.BoolField(
+		"sync-team-policies",
+		field.WithDisplayName("Sync team policies"),
+		field.WithDescription("Sync the team with its effective feature values, and a security insight on the team for each "+
+			"policy change in the team_policies category of the team event log. The insights require the \"Team event log\" "+
+			"(events.read) permission scope to be enabled on the Dropbox app."),
+		field.WithDefaultValue(false),
+	)
+	TeamPolicyInsightDaysField = field.IntField(
+		"team-policy-insight-days",
+		field.WithDisplayName("Team policy change window (days)"),
+		field.WithDescription("Number of days of team policy changes synced as insights."),
+		field.WithDefaultValue(30),
+		field.WithInt(func(r *field.IntRuler) { r.Gte(1) }),
+	)
+	SyncDeviceActivityField = field.BoolField(
+		"sync-device-activity",
+		field.WithDisplayName("Sync device activity"),
+		field.WithDescription("Emit a usage event on the Dropbox app for each member's most recent activity on their web "+
+			"sessions, desktop clients and mobile clients. A last-activity source for apps without the \"Team event log\" "+
+			"(events.read) scope; requires the \"View team sessions\" (sessions.list) permission scope to be enabled on "+
+			"the Dropbox app."),
+		field.WithDefaultValue(false),
+	)
+	StaleInviteDaysField = field.IntField(
+		"stale-invite-days",
+		field.WithDisplayName("Stale invitation age (days)"),
+		field.WithDescription("Number of days after which a pending team invitation is flagged as stale. "+
+			"Stale invitations are marked with an invitation_stale status detail and profile flag. Set to 0 to never flag invitations as stale."),
+		field.WithDefaultValue(30),
+		field.WithInt(func(r *field.IntRuler) { r.Gte(0) }),
+	)
+	ReadRateLimitField = field.IntField(
+		"read-requests-per-minute",
+		field.WithDisplayName("Read requests per minute"),
+		field.WithDescription("Client-side limit on Dropbox list and get requests per minute."),
+		field.WithDefaultValue(600),
+		field.WithInt(func(r *field.IntRuler) { r.Gte(1) }),
+	)
+	WriteRateLimitField = field.IntField(
+		"write-requests-per-minute",
+		field.WithDisplayName("Write requests per minute"),
+		field.WithDescription("Client-side limit on Dropbox requests that change team state (provisioning and actions) per minute."),
+		field.WithDefaultValue(120),
+		field.WithInt(func(r *field.IntRuler) { r.Gte(1) }),
+	)
+	TeamLogRateLimitField = field.IntField(
+		"team-log-requests-per-minute",
+		field.WithDisplayName("Team log requests per minute"),
+		field.WithDescription("Client-side limit on Dropbox team event log (team_log) requests per minute."),
+		field.WithDefaultValue(300),
+		field.WithInt(func(r *field.IntRuler) { r.Gte(1) }),
+	)
+	PageSizeField = field.IntField(
+		"page-size",
+		field.WithDisplayName("Page size"),
+		field.WithDescription("Number of members or groups fetched per Dropbox API call, up to 1000."),
+		field.WithDefaultValue(100),
+		field.WithInt(func(r *field.IntRuler) { r.Gte(1).Lte(1000) }),
+	)
+	AdaptivePageSizeField = field.BoolField(
+		"adaptive-page-size",
+		field.WithDisplayName("Adaptive page size"),
+		field.WithDescription("Grow page sizes, up to 1000, while Dropbox responds quickly, and shrink them after timeouts or rate limiting. Only listings started after a change use the new size."),
+		field.WithDefaultValue(false),
+	)
 	// ConfigurationFields defines the external configuration required for the
 	// connector to run. Note: these fields can be marked as optional or
 	// required.
@@ -61,9 +237,32 @@ var (
 		AppSecret,
 		RefreshTokenField,
 		ConfigureField,
+		ConfigureHeadlessField,
+		ConfigureRedirectPortField,
+		ConfigurePrintRefreshTokenField,
+		CredentialsFileField,
+		CredentialsPassphraseField,
 		Oauth2TokenField,
 		BaseURLField,
 		SyncUserLastLoginField,
+		SyncResourceChangeEventsField,
+		SyncGrantEventsField,
+		SyncFolderActivityField,
+		AggregateLoginEventsField,
+		LoginAggregationHoursField,
+		SyncSignInInsightsField,
+		SignInInsightDaysField,
+		SSORequiredField,
+		SyncTwoStepVerificationField,
+		SyncTeamPoliciesField,
+		TeamPolicyInsightDaysField,
+		SyncDeviceActivityField,
+		StaleInviteDaysField,
+		ReadRateLimitField,
+		WriteRateLimitField,
+		TeamLogRateLimitField,
+		PageSizeField,
+		AdaptivePageSizeField,
 	}
 )
 
@@ -3,8 +3,11 @@ package connector
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/conductorone/baton-dropbox/pkg/connector/dropbox"
 	config "github.com/conductorone/baton-sdk/pb/c1/config/v1"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/actions"
@@ -17,10 +20,20 @@ import (
 )
 
 const (
-	ActionDisableUser = "disable_user"
-	ActionEnableUser  = "enable_user"
+	ActionDisableUser                      = "disable_user"
+	ActionEnableUser                       = "enable_user"
+	ActionAddSecondaryEmail                = "add_secondary_email"
+	ActionRemoveSecondaryEmail             = "remove_secondary_email"
+	ActionResendSecondaryEmailVerification = "resend_secondary_email_verification"
+	ActionResendInvitation                 = "resend_invitation"
+	ActionCancelInvitation                 = "cancel_invitation"
+	ActionListInactiveMembers              = "list_inactive_members"
 )
 
+// inactiveMemberStatuses are the status filters list_inactive_members
+// accepts; "all" matches any status but removed.
+var inactiveMemberStatuses = []string{"active", "suspended", "invited", "all"}
+
 var disableUserActionSchema = &v2.BatonActionSchema{
 	Name:        ActionDisableUser,
 	DisplayName: "Disable User",
@@ -73,8 +86,190 @@ var enableUserActionSchema = &v2.BatonActionSchema{
 	},
 }
 
+// secondaryEmailActionArguments are shared by the secondary email actions.
+var secondaryEmailActionArguments = []*config.Field{
+	{
+		Name:        "user_id",
+		DisplayName: "User Team Member ID",
+		Description: "The team member ID of the user whose secondary email is changed",
+		Field:       &config.Field_StringField{},
+		IsRequired:  true,
+	},
+	{
+		Name:        "email",
+		DisplayName: "Secondary Email",
+		Description: "The secondary email address",
+		Field:       &config.Field_StringField{},
+		IsRequired:  true,
+	},
+}
+
+var addSecondaryEmailActionSchema = &v2.BatonActionSchema{
+	Name:        ActionAddSecondaryEmail,
+	DisplayName: "Add Secondary Email",
+	Description: "Adds a secondary email to a Dropbox Team member; Dropbox sends a verification email to the new address",
+	Arguments:   secondaryEmailActionArguments,
+	ReturnTypes: []*config.Field{
+		{
+			Name:        "success",
+			DisplayName: "Success",
+			Description: "Whether the secondary email was added successfully",
+			Field:       &config.Field_BoolField{},
+		},
+	},
+}
+
+var removeSecondaryEmailActionSchema = &v2.BatonActionSchema{
+	Name:        ActionRemoveSecondaryEmail,
+	DisplayName: "Remove Secondary Email",
+	Description: "Removes a secondary email from a Dropbox Team member",
+	Arguments:   secondaryEmailActionArguments,
+	ReturnTypes: []*config.Field{
+		{
+			Name:        "success",
+			DisplayName: "Success",
+			Description: "Whether the secondary email was removed successfully",
+			Field:       &config.Field_BoolField{},
+		},
+	},
+}
+
+var resendSecondaryEmailVerificationActionSchema = &v2.BatonActionSchema{
+	Name:        ActionResendSecondaryEmailVerification,
+	DisplayName: "Resend Secondary Email Verification",
+	Description: "Resends the verification email for a Dropbox Team member's pending secondary email",
+	Arguments:   secondaryEmailActionArguments,
+	ReturnTypes: []*config.Field{
+		{
+			Name:        "success",
+			DisplayName: "Success",
+			Description: "Whether the verification email was resent successfully",
+			Field:       &config.Field_BoolField{},
+		},
+	},
+}
+
+var resendInvitationActionSchema = &v2.BatonActionSchema{
+	Name:        ActionResendInvitation,
+	DisplayName: "Resend Invitation",
+	Description: "Resends the Dropbox Team invitation email to a member whose invitation is still pending",
+	Arguments: []*config.Field{
+		{
+			Name:        "user_id",
+			DisplayName: "User Team Member ID",
+			Description: "The team member ID of the invited user",
+			Field:       &config.Field_StringField{},
+			IsRequired:  true,
+		},
+	},
+	ReturnTypes: []*config.Field{
+		{
+			Name:        "success",
+			DisplayName: "Success",
+			Description: "Whether the invitation was resent successfully",
+			Field:       &config.Field_BoolField{},
+		},
+	},
+}
+
+var cancelInvitationActionSchema = &v2.BatonActionSchema{
+	Name:        ActionCancelInvitation,
+	DisplayName: "Cancel Invitation",
+	Description: "Cancels a pending Dropbox Team invitation by removing the invited member",
+	Arguments: []*config.Field{
+		{
+			Name:        "user_id",
+			DisplayName: "User Team Member ID",
+			Description: "The team member ID of the invited user",
+			Field:       &config.Field_StringField{},
+			IsRequired:  true,
+		},
+	},
+	ReturnTypes: []*config.Field{
+		{
+			Name:        "success",
+			DisplayName: "Success",
+			Description: "Whether the invitation was cancelled successfully",
+			Field:       &config.Field_BoolField{},
+		},
+	},
+	ActionType: []v2.ActionType{
+		v2.ActionType_ACTION_TYPE_RESOURCE_DELETE,
+	},
+}
+
+var listInactiveMembersActionSchema = &v2.BatonActionSchema{
+	Name:        ActionListInactiveMembers,
+	DisplayName: "List Inactive Members",
+	Description: "Lists Dropbox Team members with no successful sign-in within the given number of days, a page of members per call. Each member's sign-ins are read from the team event log on every call; no last-login index is kept, since actions have no session store to keep one in",
+	Arguments: []*config.Field{
+		{
+			Name:        "inactive_days",
+			DisplayName: "Inactive Days",
+			Description: "Members who haven't signed in for this many days are listed",
+			Field:       &config.Field_IntField{IntField: &config.IntField{DefaultValue: 90}},
+			IsRequired:  true,
+		},
+		{
+			Name:        "status",
+			DisplayName: "Member Status",
+			Description: "Only list members with this status: active (the default), suspended, invited or all",
+			Field:       &config.Field_StringField{StringField: &config.StringField{DefaultValue: "active"}},
+		},
+		{
+			Name:        "page_token",
+			DisplayName: "Page Token",
+			Description: "The next_page_token of the previous call, to continue a listing with the same arguments",
+			Field:       &config.Field_StringField{},
+		},
+	},
+	ReturnTypes: []*config.Field{
+		{
+			Name:        "success",
+			DisplayName: "Success",
+			Description: "Whether the inactive members were listed successfully",
+			Field:       &config.Field_BoolField{},
+		},
+		{
+			Name:        "team_member_ids",
+			DisplayName: "Team Member IDs",
+			Description: "The team member IDs of the inactive members in this page",
+			Field:       &config.Field_StringSliceField{},
+		},
+		{
+			Name:        "emails",
+			DisplayName: "Emails",
+			Description: "The emails of the inactive members, in the same order",
+			Field:       &config.Field_StringSliceField{},
+		},
+		{
+			Name:        "last_seen",
+			DisplayName: "Last Seen",
+			Description: "Each inactive member's last successful sign-in, in the same order; empty if there is none in the 90 days before the threshold",
+			Field:       &config.Field_StringSliceField{},
+		},
+		{
+			Name:        "count",
+			DisplayName: "Count",
+			Description: "The number of inactive members in this page",
+			Field:       &config.Field_IntField{},
+		},
+		{
+			Name:        "next_page_token",
+			DisplayName: "Next Page Token",
+			Description: "Pass as page_token to continue the listing; empty once every member has been checked",
+			Field:       &config.Field_StringField{},
+		},
+	},
+}
+
 // extractUserID extracts and validates the user_id from action arguments.
 func extractUserID(ctx context.Context, args *structpb.Struct, actionName string) (string, error) {
+	return extractStringArg(ctx, args, actionName, "user_id")
+}
+
+// extractStringArg extracts and validates a required, non-empty string argument.
+func extractStringArg(ctx context.Context, args *structpb.Struct, actionName string, name string) (string, error) {
 	l := ctxzap.Extract(ctx)
 
 	if args == nil || args.Fields == nil {
@@ -82,35 +277,79 @@ func extractUserID(ctx context.Context, args *structpb.Struct, actionName string
 		return "", status.Errorf(codes.InvalidArgument, "invalid arguments")
 	}
 
-	resourceIDValue, exists := args.Fields["user_id"]
-	if !exists || resourceIDValue == nil {
-		l.Error("missing user ID", zap.String("action", actionName))
-		return "", status.Errorf(codes.InvalidArgument, "missing user_id")
+	value, exists := args.Fields[name]
+	if !exists || value == nil {
+		l.Error("missing argument", zap.String("action", actionName), zap.String("argument", name))
+		return "", status.Errorf(codes.InvalidArgument, "missing %s", name)
 	}
 
-	uidField, ok := resourceIDValue.GetKind().(*structpb.Value_StringValue)
+	stringField, ok := value.GetKind().(*structpb.Value_StringValue)
 	if !ok {
-		l.Error("invalid user ID format", zap.String("action", actionName))
-		return "", status.Errorf(codes.InvalidArgument, "invalid user_id format")
+		l.Error("invalid argument format", zap.String("action", actionName), zap.String("argument", name))
+		return "", status.Errorf(codes.InvalidArgument, "invalid %s format", name)
 	}
 
-	teamMemberID := uidField.StringValue
-	if teamMemberID == "" {
-		l.Error("empty user ID", zap.String("action", actionName))
-		return "", status.Errorf(codes.InvalidArgument, "user_id cannot be empty")
+	if stringField.StringValue == "" {
+		l.Error("empty argument", zap.String("action", actionName), zap.String("argument", name))
+		return "", status.Errorf(codes.InvalidArgument, "%s cannot be empty", name)
 	}
 
-	return teamMemberID, nil
+	return stringField.StringValue, nil
 }
 
-// RegisterActionManager registers custom actions for the Dropbox connector.
-func (c *Connector) GlobalActions(ctx context.Context, registry actions.ActionRegistry) error {
-	if err := registry.Register(ctx, disableUserActionSchema, c.disableUserActionHandler); err != nil {
-		return fmt.Errorf("failed to register disable user action: %w", err)
+// extractSecondaryEmailArgs extracts the user_id and email arguments shared by
+// the secondary email actions.
+func extractSecondaryEmailArgs(ctx context.Context, args *structpb.Struct, actionName string) (string, string, error) {
+	teamMemberID, err := extractUserID(ctx, args, actionName)
+	if err != nil {
+		return "", "", err
+	}
+
+	email, err := extractStringArg(ctx, args, actionName, "email")
+	if err != nil {
+		return "", "", err
+	}
+
+	return teamMemberID, email, nil
+}
+
+// globalAction pairs an action with its handler and the scopes it needs.
+type globalAction struct {
+	schema  *v2.BatonActionSchema
+	handler actions.ActionHandler
+	scopes  []string
+}
+
+// globalActions lists the connector's global actions. Per the Dropbox API
+// spec, suspend/unsuspend, secondary email and send_welcome_email need
+// members.write; cancelling an invitation removes the member, which needs
+// members.delete. Listing inactive members reads the team event log, which
+// needs events.read, so it is only offered with sync-user-last-login.
+func (c *Connector) globalActions() []globalAction {
+	globalActions := []globalAction{
+		{disableUserActionSchema, c.disableUserActionHandler, []string{"members.write"}},
+		{enableUserActionSchema, c.enableUserActionHandler, []string{"members.write"}},
+		{addSecondaryEmailActionSchema, c.addSecondaryEmailActionHandler, []string{"members.write"}},
+		{removeSecondaryEmailActionSchema, c.removeSecondaryEmailActionHandler, []string{"members.write"}},
+		{resendSecondaryEmailVerificationActionSchema, c.resendSecondaryEmailVerificationActionHandler, []string{"members.write"}},
+		{resendInvitationActionSchema, c.resendInvitationActionHandler, []string{"members.read", "members.write"}},
+		{cancelInvitationActionSchema, c.cancelInvitationActionHandler, []string{"members.read", "members.delete"}},
+	}
+	if c.syncUserLastLogin {
+		globalActions = append(globalActions, globalAction{listInactiveMembersActionSchema, c.listInactiveMembersActionHandler, []string{"members.read", "events.read"}})
 	}
+	return globalActions
+}
 
-	if err := registry.Register(ctx, enableUserActionSchema, c.enableUserActionHandler); err != nil {
-		return fmt.Errorf("failed to register enable user action: %w", err)
+// GlobalActions registers the custom actions whose scopes were granted.
+func (c *Connector) GlobalActions(ctx context.Context, registry actions.ActionRegistry) error {
+	for _, action := range c.globalActions() {
+		if !c.scopesGranted(action.scopes...) {
+			continue
+		}
+		if err := registry.Register(ctx, action.schema, action.handler); err != nil {
+			return fmt.Errorf("failed to register %s action: %w", action.schema.GetName(), err)
+		}
 	}
 
 	return nil
@@ -129,7 +368,7 @@ func (c *Connector) disableUserActionHandler(ctx context.Context, args *structpb
 
 	_, err = c.client.SuspendMember(ctx, teamMemberID)
 	if err != nil {
-		if strings.Contains(err.Error(), "suspend_inactive_user") {
+		if dropbox.HasErrorTag(err, "suspend_inactive_user") {
 			l.Info("user is already disabled", zap.String("team_member_id", teamMemberID))
 			return getResponseStruct(true), nil, nil
 		}
@@ -154,7 +393,7 @@ func (c *Connector) enableUserActionHandler(ctx context.Context, args *structpb.
 
 	_, err = c.client.UnsuspendMember(ctx, teamMemberID)
 	if err != nil {
-		if strings.Contains(err.Error(), "unsuspend_non_suspended_member") {
+		if dropbox.HasErrorTag(err, "unsuspend_non_suspended_member") {
 			l.Info("user is already enabled", zap.String("team_member_id", teamMemberID))
 			return getResponseStruct(true), nil, nil
 		}
@@ -166,6 +405,317 @@ func (c *Connector) enableUserActionHandler(ctx context.Context, args *structpb.
 	return getResponseStruct(true), nil, nil
 }
 
+// addSecondaryEmailActionHandler handles the add secondary email action.
+func (c *Connector) addSecondaryEmailActionHandler(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	teamMemberID, email, err := extractSecondaryEmailArgs(ctx, args, ActionAddSecondaryEmail)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	l.Info("adding secondary email", zap.String("team_member_id", teamMemberID))
+
+	payload, rateLimitData, err := c.client.AddSecondaryEmails(ctx, teamMemberID, []string{email})
+	var annos annotations.Annotations
+	annos.WithRateLimiting(rateLimitData)
+	if err != nil {
+		l.Error("failed to add secondary email", zap.String("team_member_id", teamMemberID), zap.Error(err))
+		return nil, annos, fmt.Errorf("failed to add secondary email: %w", err)
+	}
+
+	result, err := secondaryEmailResult(payload)
+	if err != nil {
+		return nil, annos, fmt.Errorf("failed to add secondary email: %w", err)
+	}
+
+	switch result.Tag {
+	case "success":
+	case "already_pending", "already_owned_by_user":
+		l.Info("secondary email already present on user", zap.String("team_member_id", teamMemberID), zap.String("result", result.Tag))
+	case "unavailable", "reached_limit":
+		return nil, annos, status.Errorf(codes.FailedPrecondition, "failed to add secondary email: %s", result.Tag)
+	case "rate_limited", "too_many_updates", "transient_error":
+		return nil, annos, status.Errorf(codes.Unavailable, "failed to add secondary email: %s", result.Tag)
+	default:
+		return nil, annos, fmt.Errorf("failed to add secondary email: %s", result.Tag)
+	}
+
+	l.Info("secondary email added successfully", zap.String("team_member_id", teamMemberID))
+	return getResponseStruct(true), annos, nil
+}
+
+// removeSecondaryEmailActionHandler handles the remove secondary email action.
+func (c *Connector) removeSecondaryEmailActionHandler(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	teamMemberID, email, err := extractSecondaryEmailArgs(ctx, args, ActionRemoveSecondaryEmail)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	l.Info("removing secondary email", zap.String("team_member_id", teamMemberID))
+
+	payload, rateLimitData, err := c.client.DeleteSecondaryEmails(ctx, teamMemberID, []string{email})
+	var annos annotations.Annotations
+	annos.WithRateLimiting(rateLimitData)
+	if err != nil {
+		l.Error("failed to remove secondary email", zap.String("team_member_id", teamMemberID), zap.Error(err))
+		return nil, annos, fmt.Errorf("failed to remove secondary email: %w", err)
+	}
+
+	result, err := secondaryEmailResult(payload)
+	if err != nil {
+		return nil, annos, fmt.Errorf("failed to remove secondary email: %w", err)
+	}
+
+	switch result.Tag {
+	case "success":
+	case "not_found":
+		l.Info("secondary email already removed from user", zap.String("team_member_id", teamMemberID))
+	case "cannot_remove_primary":
+		return nil, annos, status.Errorf(codes.FailedPrecondition, "failed to remove secondary email: %s is the user's primary email", email)
+	default:
+		return nil, annos, fmt.Errorf("failed to remove secondary email: %s", result.Tag)
+	}
+
+	l.Info("secondary email removed successfully", zap.String("team_member_id", teamMemberID))
+	return getResponseStruct(true), annos, nil
+}
+
+// resendSecondaryEmailVerificationActionHandler handles the resend secondary email verification action.
+func (c *Connector) resendSecondaryEmailVerificationActionHandler(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	teamMemberID, email, err := extractSecondaryEmailArgs(ctx, args, ActionResendSecondaryEmailVerification)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	l.Info("resending secondary email verification", zap.String("team_member_id", teamMemberID))
+
+	payload, rateLimitData, err := c.client.ResendSecondaryEmailVerification(ctx, teamMemberID, []string{email})
+	var annos annotations.Annotations
+	annos.WithRateLimiting(rateLimitData)
+	if err != nil {
+		l.Error("failed to resend secondary email verification", zap.String("team_member_id", teamMemberID), zap.Error(err))
+		return nil, annos, fmt.Errorf("failed to resend secondary email verification: %w", err)
+	}
+
+	result, err := secondaryEmailResult(payload)
+	if err != nil {
+		return nil, annos, fmt.Errorf("failed to resend secondary email verification: %w", err)
+	}
+
+	switch result.Tag {
+	case "success":
+	case "not_pending":
+		return nil, annos, status.Errorf(codes.FailedPrecondition, "failed to resend secondary email verification: %s is not pending verification", email)
+	case "rate_limited":
+		return nil, annos, status.Errorf(codes.Unavailable, "failed to resend secondary email verification: %s", result.Tag)
+	default:
+		return nil, annos, fmt.Errorf("failed to resend secondary email verification: %s", result.Tag)
+	}
+
+	l.Info("secondary email verification resent successfully", zap.String("team_member_id", teamMemberID))
+	return getResponseStruct(true), annos, nil
+}
+
+// secondaryEmailResult returns the outcome for the single user and email sent
+// by the secondary email actions.
+func secondaryEmailResult(payload *dropbox.SecondaryEmailsPayload) (dropbox.SecondaryEmailResult, error) {
+	if payload == nil || len(payload.Results) == 0 {
+		return dropbox.SecondaryEmailResult{}, fmt.Errorf("received empty response from Dropbox API")
+	}
+
+	userResult := payload.Results[0]
+	if userResult.Tag != "success" {
+		if userResult.Tag == "invalid_user" {
+			return dropbox.SecondaryEmailResult{}, status.Errorf(codes.NotFound, "user not found")
+		}
+		return dropbox.SecondaryEmailResult{}, fmt.Errorf("unexpected user result: %s", userResult.Tag)
+	}
+
+	if len(userResult.Results) == 0 {
+		return dropbox.SecondaryEmailResult{}, fmt.Errorf("received empty email result from Dropbox API")
+	}
+
+	return userResult.Results[0], nil
+}
+
+// resendInvitationActionHandler handles the resend invitation action.
+func (c *Connector) resendInvitationActionHandler(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	teamMemberID, err := extractUserID(ctx, args, ActionResendInvitation)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	annos, err := c.requireInvitedMember(ctx, teamMemberID)
+	if err != nil {
+		return nil, annos, err
+	}
+
+	l.Info("resending invitation", zap.String("team_member_id", teamMemberID))
+
+	rateLimitData, err := c.client.SendWelcomeEmail(ctx, teamMemberID)
+	annos.WithRateLimiting(rateLimitData)
+	if err != nil {
+		l.Error("failed to resend invitation", zap.String("team_member_id", teamMemberID), zap.Error(err))
+		return nil, annos, fmt.Errorf("failed to resend invitation: %w", err)
+	}
+
+	l.Info("invitation resent successfully", zap.String("team_member_id", teamMemberID))
+	return getResponseStruct(true), annos, nil
+}
+
+// cancelInvitationActionHandler handles the cancel invitation action. Dropbox
+// has no dedicated endpoint for this; an invitation is cancelled by removing
+// the invited member, which is only done after confirming the member hasn't
+// joined so the action can never remove an active account.
+func (c *Connector) cancelInvitationActionHandler(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	teamMemberID, err := extractUserID(ctx, args, ActionCancelInvitation)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	annos, err := c.requireInvitedMember(ctx, teamMemberID)
+	if err != nil {
+		return nil, annos, err
+	}
+
+	l.Info("cancelling invitation", zap.String("team_member_id", teamMemberID))
+
+	_, rateLimitData, err := c.client.RemoveMember(ctx, teamMemberID)
+	annos.WithRateLimiting(rateLimitData)
+	if err != nil {
+		l.Error("failed to cancel invitation", zap.String("team_member_id", teamMemberID), zap.Error(err))
+		return nil, annos, fmt.Errorf("failed to cancel invitation: %w", err)
+	}
+
+	l.Info("invitation cancelled successfully", zap.String("team_member_id", teamMemberID))
+	return getResponseStruct(true), annos, nil
+}
+
+// listInactiveMembersActionHandler handles the list inactive members action.
+// Each call checks one page of inactiveMembersPageSize team members, reading
+// each candidate's sign-ins from the team event log, and returns a token for
+// the next page. Members who joined, or were invited, within the threshold
+// aren't inactive yet.
+func (c *Connector) listInactiveMembersActionHandler(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	inactiveDays, ok := actions.GetIntArg(args, "inactive_days")
+	if !ok || inactiveDays < 1 {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "inactive_days must be a positive number of days")
+	}
+	statusFilter, ok := actions.GetStringArg(args, "status")
+	if !ok || statusFilter == "" {
+		statusFilter = "active"
+	}
+	if !slices.Contains(inactiveMemberStatuses, statusFilter) {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "status must be one of %s", strings.Join(inactiveMemberStatuses, ", "))
+	}
+	pageToken, _ := actions.GetStringArg(args, "page_token")
+	pt, err := unmarshalInactiveMembersPageToken(pageToken)
+	if err != nil {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "page_token is not a token returned by this action")
+	}
+
+	if pt.Cutoff == 0 {
+		pt.Cutoff = time.Now().Add(-time.Duration(inactiveDays) * 24 * time.Hour).Unix()
+	}
+	cutoff := time.Unix(pt.Cutoff, 0)
+	l.Info("listing inactive members", zap.Int64("inactive_days", inactiveDays), zap.String("status", statusFilter), zap.Bool("continued", pageToken != ""))
+
+	var annos annotations.Annotations
+	members, nextToken, rateLimitData, err := nextMembersPage(ctx, c.client, listingSession{}, inactiveMembersPageSize, pt.MembersToken)
+	annos.WithRateLimiting(rateLimitData)
+	if err != nil {
+		return nil, annos, fmt.Errorf("failed to list inactive members: %w", err)
+	}
+
+	teamMemberIDs, emails, lastSeen := []string{}, []string{}, []string{}
+	for _, member := range members {
+		profile := member.Profile
+		if profile.Status.Tag == "removed" || (statusFilter != "all" && profile.Status.Tag != statusFilter) {
+			continue
+		}
+		if memberSince(profile).After(cutoff) {
+			continue
+		}
+
+		seen, rateLimitData, err := memberLastLogin(ctx, c.client, profile.AccountID, cutoff)
+		annos.WithRateLimiting(rateLimitData)
+		if err != nil {
+			return nil, annos, fmt.Errorf("failed to list inactive members: %w", err)
+		}
+		if !seen.Before(cutoff) {
+			continue
+		}
+		teamMemberIDs = append(teamMemberIDs, profile.TeamMemberID)
+		emails = append(emails, profile.Email)
+		if seen.IsZero() {
+			lastSeen = append(lastSeen, "")
+		} else {
+			lastSeen = append(lastSeen, seen.UTC().Format(dropbox.TimestampFormat))
+		}
+	}
+
+	nextPageToken := ""
+	if nextToken != "" {
+		pt.MembersToken = nextToken
+		nextPageToken, err = pt.marshal()
+		if err != nil {
+			return nil, annos, fmt.Errorf("dropbox-connector: failed to marshal page token: %w", err)
+		}
+	}
+
+	l.Info("listed inactive members", zap.Int("count", len(teamMemberIDs)), zap.Bool("done", nextPageToken == ""))
+	return actions.NewReturnValues(true,
+		actions.NewStringListReturnField("team_member_ids", teamMemberIDs),
+		actions.NewStringListReturnField("emails", emails),
+		actions.NewStringListReturnField("last_seen", lastSeen),
+		actions.NewNumberReturnField("count", float64(len(teamMemberIDs))),
+		actions.NewStringReturnField("next_page_token", nextPageToken),
+	), annos, nil
+}
+
+// memberSince returns when a member joined or, for invited members, was
+// invited; the zero time if neither is known.
+func memberSince(profile dropbox.Profile) time.Time {
+	for _, value := range []string{profile.JoinedOn, profile.InvitedOn} {
+		if since, err := time.Parse(dropbox.TimestampFormat, value); err == nil {
+			return since
+		}
+	}
+	return time.Time{}
+}
+
+// requireInvitedMember returns an error unless teamMemberID identifies a
+// member whose invitation is still pending.
+func (c *Connector) requireInvitedMember(ctx context.Context, teamMemberID string) (annotations.Annotations, error) {
+	member, rateLimitData, err := c.client.GetMemberInfo(ctx, teamMemberID)
+	var annos annotations.Annotations
+	annos.WithRateLimiting(rateLimitData)
+	if err != nil {
+		return annos, fmt.Errorf("failed to look up member: %w", err)
+	}
+
+	if member == nil {
+		return annos, status.Errorf(codes.NotFound, "user not found")
+	}
+
+	if member.Profile.Status.Tag != "invited" {
+		return annos, status.Errorf(codes.FailedPrecondition, "user has no pending invitation (status: %s)", member.Profile.Status.Tag)
+	}
+
+	return annos, nil
+}
+
 // getResponseStruct creates a standard response struct for action results.
 func getResponseStruct(success bool) *structpb.Struct {
 	return &structpb.Struct{
@@ -0,0 +1,191 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/conductorone/baton-dropbox/pkg/connector/dropbox"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// newInvitationServer serves get_info_v2 in Dropbox's wire shape, where the
+// member_info variant's fields sit next to its tag, and records the members
+// sent welcome emails or removed.
+func newInvitationServer(t *testing.T, resent, removed *[]string) *httptest.Server {
+	t.Helper()
+
+	members := map[string]string{
+		"dbmid:invited": `{".tag": "member_info", "profile": {"team_member_id": "dbmid:invited", "email": "invited@example.com", "status": {".tag": "invited"}}, "roles": []}`,
+		"dbmid:active":  `{".tag": "member_info", "profile": {"team_member_id": "dbmid:active", "email": "active@example.com", "status": {".tag": "active"}}, "roles": []}`,
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var body struct {
+			Members []dropbox.TeamMemberIdTag `json:"members"`
+			User    dropbox.TeamMemberIdTag   `json:"user"`
+			dropbox.TeamMemberIdTag
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		switch r.URL.Path {
+		case "/2/team/members/get_info_v2":
+			require.Len(t, body.Members, 1)
+			item, ok := members[body.Members[0].TeamMemberID]
+			if !ok {
+				item = `{".tag": "id_not_found", "id_not_found": "` + body.Members[0].TeamMemberID + `"}`
+			}
+			_, _ = w.Write([]byte(`{"members_info": [` + item + `]}`))
+		case "/2/team/members/send_welcome_email":
+			*resent = append(*resent, body.TeamMemberID)
+			_, _ = w.Write([]byte(`null`))
+		case "/2/team/members/remove":
+			*removed = append(*removed, body.User.TeamMemberID)
+			_, _ = w.Write([]byte(`{".tag": "complete"}`))
+		default:
+			t.Fatalf("unexpected request path: %s", r.URL.Path)
+		}
+	}))
+}
+
+func TestConnector_InvitationActions(t *testing.T) {
+	var resent, removed []string
+	server := newInvitationServer(t, &resent, &removed)
+	defer server.Close()
+
+	c := newTestConnector(t, server)
+	args := func(userID string) *structpb.Struct {
+		s, err := structpb.NewStruct(map[string]any{"user_id": userID})
+		require.NoError(t, err)
+		return s
+	}
+
+	_, _, err := c.resendInvitationActionHandler(context.Background(), args("dbmid:invited"))
+	require.NoError(t, err)
+	_, _, err = c.cancelInvitationActionHandler(context.Background(), args("dbmid:invited"))
+	require.NoError(t, err)
+
+	_, _, err = c.cancelInvitationActionHandler(context.Background(), args("dbmid:active"))
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, _, err = c.resendInvitationActionHandler(context.Background(), args("dbmid:unknown"))
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	require.Equal(t, []string{"dbmid:invited"}, resent)
+	require.Equal(t, []string{"dbmid:invited"}, removed)
+}
+
+// newSecondaryEmailServer answers the secondary_emails endpoints with the
+// result tag named by the local part of the requested email, e.g.
+// not_found@example.com gets a not_found result. dbmid:unknown is an
+// invalid_user, dbmid:none gets no user results and dbmid:noemails gets no
+// email results.
+func newSecondaryEmailServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var body map[string][]dropbox.UserSecondaryEmailsArg
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		var key string
+		switch r.URL.Path {
+		case "/2/team/members/secondary_emails/add":
+			key = "new_secondary_emails"
+		case "/2/team/members/secondary_emails/delete":
+			key = "emails_to_delete"
+		case "/2/team/members/secondary_emails/resend_verification_emails":
+			key = "emails_to_resend"
+		default:
+			t.Fatalf("unexpected request path: %s", r.URL.Path)
+		}
+		require.Len(t, body[key], 1)
+		arg := body[key][0]
+		require.Len(t, arg.SecondaryEmails, 1)
+		email := arg.SecondaryEmails[0]
+
+		switch arg.User.TeamMemberID {
+		case "dbmid:unknown":
+			_, _ = w.Write([]byte(`{"results": [{".tag": "invalid_user", "invalid_user": {".tag": "team_member_id", "team_member_id": "dbmid:unknown"}}]}`))
+		case "dbmid:none":
+			_, _ = w.Write([]byte(`{"results": []}`))
+		case "dbmid:noemails":
+			_, _ = w.Write([]byte(`{"results": [{".tag": "success", "results": []}]}`))
+		default:
+			tag := strings.TrimSuffix(email, "@example.com")
+			_, _ = fmt.Fprintf(w, `{"results": [{".tag": "success", "results": [{".tag": %q, %q: %q}]}]}`, tag, tag, email)
+		}
+	}))
+}
+
+func TestConnector_SecondaryEmailActions(t *testing.T) {
+	server := newSecondaryEmailServer(t)
+	defer server.Close()
+
+	c := newTestConnector(t, server)
+	c.client.SetRateLimits(map[dropbox.EndpointClass]dropbox.RateLimit{dropbox.EndpointClassWrite: {RequestsPerMinute: 6000, Burst: 10}})
+	handlers := map[string]func(context.Context, *structpb.Struct) (*structpb.Struct, annotations.Annotations, error){
+		ActionAddSecondaryEmail:                c.addSecondaryEmailActionHandler,
+		ActionRemoveSecondaryEmail:             c.removeSecondaryEmailActionHandler,
+		ActionResendSecondaryEmailVerification: c.resendSecondaryEmailVerificationActionHandler,
+	}
+
+	tests := []struct {
+		action string
+		userID string
+		result string
+		code   codes.Code
+	}{
+		{ActionAddSecondaryEmail, "dbmid:1", "success", codes.OK},
+		{ActionAddSecondaryEmail, "dbmid:1", "already_pending", codes.OK},
+		{ActionAddSecondaryEmail, "dbmid:1", "already_owned_by_user", codes.OK},
+		{ActionAddSecondaryEmail, "dbmid:1", "unavailable", codes.FailedPrecondition},
+		{ActionAddSecondaryEmail, "dbmid:1", "reached_limit", codes.FailedPrecondition},
+		{ActionAddSecondaryEmail, "dbmid:1", "rate_limited", codes.Unavailable},
+		{ActionAddSecondaryEmail, "dbmid:1", "unknown_error", codes.Unknown},
+		{ActionAddSecondaryEmail, "dbmid:unknown", "success", codes.NotFound},
+		{ActionRemoveSecondaryEmail, "dbmid:1", "success", codes.OK},
+		{ActionRemoveSecondaryEmail, "dbmid:1", "not_found", codes.OK},
+		{ActionRemoveSecondaryEmail, "dbmid:1", "cannot_remove_primary", codes.FailedPrecondition},
+		{ActionRemoveSecondaryEmail, "dbmid:1", "unknown_error", codes.Unknown},
+		{ActionRemoveSecondaryEmail, "dbmid:unknown", "success", codes.NotFound},
+		{ActionResendSecondaryEmailVerification, "dbmid:1", "success", codes.OK},
+		{ActionResendSecondaryEmailVerification, "dbmid:1", "not_pending", codes.FailedPrecondition},
+		{ActionResendSecondaryEmailVerification, "dbmid:1", "rate_limited", codes.Unavailable},
+		{ActionResendSecondaryEmailVerification, "dbmid:unknown", "success", codes.NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action+"/"+tt.userID+"/"+tt.result, func(t *testing.T) {
+			args, err := structpb.NewStruct(map[string]any{"user_id": tt.userID, "email": tt.result + "@example.com"})
+			require.NoError(t, err)
+
+			result, _, err := handlers[tt.action](context.Background(), args)
+			require.Equal(t, tt.code, status.Code(err), "error: %v", err)
+			if tt.code == codes.OK {
+				require.Equal(t, true, result.AsMap()["success"])
+			}
+		})
+	}
+
+	for action, handler := range handlers {
+		args, err := structpb.NewStruct(map[string]any{"user_id": "dbmid:none", "email": "success@example.com"})
+		require.NoError(t, err)
+		_, _, err = handler(context.Background(), args)
+		require.ErrorContains(t, err, "received empty response", action)
+
+		args, err = structpb.NewStruct(map[string]any{"user_id": "dbmid:noemails", "email": "success@example.com"})
+		require.NoError(t, err)
+		_, _, err = handler(context.Background(), args)
+		require.ErrorContains(t, err, "received empty email result", action)
+	}
+}
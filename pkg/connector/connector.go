@@ -6,6 +6,8 @@ import (
 	"io"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	cfg "github.com/conductorone/baton-dropbox/pkg/config"
 	"github.com/conductorone/baton-dropbox/pkg/connector/dropbox"
@@ -17,15 +19,35 @@ import (
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
 	"golang.org/x/oauth2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var _ connectorbuilder.GlobalActionProvider = (*Connector)(nil)
 var _ connectorbuilder.EventFeedsLimited = (*Connector)(nil)
 
 type Connector struct {
-	client            *dropbox.Client
-	syncUserLastLogin bool
-	syncLicenses      bool
+	client                  *dropbox.Client
+	syncUserLastLogin       bool
+	loginAggregation        loginAggregation
+	syncResourceChanges     bool
+	syncGrantEvents         bool
+	syncFolderActivity      bool
+	syncSignInInsights      bool
+	signInInsightWindow     time.Duration
+	ssoRequired             bool
+	syncTwoStepVerification bool
+	syncTeamPolicies        bool
+	teamPolicyInsightWindow time.Duration
+	syncDeviceActivity      bool
+	syncLicenses            bool
+	staleInviteAge          time.Duration
+	// missingScopes are the required scopes known not to be granted; see
+	// discoverScopes.
+	missingScopes    []string
+	rateLimits       map[dropbox.EndpointClass]dropbox.RateLimit
+	pageSize         int
+	adaptivePageSize bool
 }
 
 // Option is a function that configures a Connector.
@@ -48,11 +70,14 @@ func WithRefreshToken(ctx context.Context, appKey, appSecret, refreshToken, base
 			return fmt.Errorf("error creating dropbox client: %w", err)
 		}
 
-		accessToken, _, err := client.RequestAccessTokenUsingRefreshToken(ctx)
-		if err != nil {
+		// Access tokens only live a few hours, so refresh them as they expire
+		// rather than holding one for the whole sync. Fetch the first one now
+		// so a bad refresh token fails at startup.
+		tokenSource := dropbox.NewRefreshTokenSource(ctx, client)
+		if _, err := tokenSource.Token(); err != nil {
 			return fmt.Errorf("dropbox-connector: error getting access token using refresh token: %w", err)
 		}
-		client.TokenSource = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: accessToken})
+		client.TokenSource = tokenSource
 		c.client = client
 		return nil
 	}
@@ -67,6 +92,89 @@ func WithSyncUserLastLogin(enabled bool) Option {
 	}
 }
 
+// WithLoginAggregation collapses last-login usage events to each member's
+// newest sign-in per window, or per page of the event log if window is zero.
+func WithLoginAggregation(enabled bool, window time.Duration) Option {
+	return func(c *Connector) error {
+		c.loginAggregation = loginAggregation{enabled: enabled, window: window}
+		return nil
+	}
+}
+
+// WithSyncResourceChanges enables emitting resource change events for members,
+// groups and team folders derived from the Dropbox team event log, and
+// syncing the folders. Requires the events.read scope, and team_data.member
+// for team folders.
+func WithSyncResourceChanges(enabled bool) Option {
+	return func(c *Connector) error {
+		c.syncResourceChanges = enabled
+		return nil
+	}
+}
+
+// WithSyncGrantEvents enables emitting grant and revoke events for group
+// memberships and admin roles derived from the Dropbox team event log.
+// Requires the events.read scope.
+func WithSyncGrantEvents(enabled bool) Option {
+	return func(c *Connector) error {
+		c.syncGrantEvents = enabled
+		return nil
+	}
+}
+
+// WithSyncFolderActivity enables emitting usage events for members working in
+// team folders and shared folders, derived from the Dropbox team event log,
+// and syncing the folders they target. Requires the events.read and
+// team_data.member scopes.
+func WithSyncFolderActivity(enabled bool) Option {
+	return func(c *Connector) error {
+		c.syncFolderActivity = enabled
+		return nil
+	}
+}
+
+// WithSignInInsights enables syncing security insights derived from the
+// Dropbox team event log's sign-ins over window. Requires the events.read
+// scope.
+func WithSignInInsights(enabled bool, window time.Duration, ssoRequired bool) Option {
+	return func(c *Connector) error {
+		c.syncSignInInsights = enabled
+		c.signInInsightWindow = window
+		c.ssoRequired = ssoRequired
+		return nil
+	}
+}
+
+// WithSyncTwoStepVerification enables adding members' two-step verification
+// posture, derived from the Dropbox team event log, to their profiles.
+// Requires the events.read scope.
+func WithSyncTwoStepVerification(enabled bool) Option {
+	return func(c *Connector) error {
+		c.syncTwoStepVerification = enabled
+		return nil
+	}
+}
+
+// WithTeamPolicies enables syncing the team with its feature values and
+// security insights for the team policy changes over window recorded in the
+// Dropbox team event log. The insights require the events.read scope.
+func WithTeamPolicies(enabled bool, window time.Duration) Option {
+	return func(c *Connector) error {
+		c.syncTeamPolicies = enabled
+		c.teamPolicyInsightWindow = window
+		return nil
+	}
+}
+
+// WithSyncDeviceActivity enables the usage event feed derived from members'
+// device activity. Requires the sessions.list scope, not events.read.
+func WithSyncDeviceActivity(enabled bool) Option {
+	return func(c *Connector) error {
+		c.syncDeviceActivity = enabled
+		return nil
+	}
+}
+
 // WithSyncLicenses reports whether the "license" resource type is included in
 // the customer's sync filter. userBuilder.Grants emits license grants as a
 // cross-type optimization and must skip that work when license is filtered
@@ -78,6 +186,33 @@ func WithSyncLicenses(enabled bool) Option {
 	}
 }
 
+// WithStaleInviteAge sets how long a team invitation may stay pending before
+// the invited member is flagged as stale. Zero disables stale detection.
+func WithStaleInviteAge(age time.Duration) Option {
+	return func(c *Connector) error {
+		c.staleInviteAge = age
+		return nil
+	}
+}
+
+// WithRateLimits overrides the client-side rate limits per endpoint class.
+func WithRateLimits(limits map[dropbox.EndpointClass]dropbox.RateLimit) Option {
+	return func(c *Connector) error {
+		c.rateLimits = limits
+		return nil
+	}
+}
+
+// WithPageSize sets the member and group page size and whether page sizes
+// adapt to Dropbox's response times.
+func WithPageSize(size int, adaptive bool) Option {
+	return func(c *Connector) error {
+		c.pageSize = size
+		c.adaptivePageSize = adaptive
+		return nil
+	}
+}
+
 // WithTokenSource configures the connector to use a pre-configured token source.
 func WithTokenSource(ctx context.Context, appKey, baseURL string, tokenSource oauth2.TokenSource) Option {
 	return func(c *Connector) error {
@@ -111,6 +246,10 @@ func NewLambdaConnector(ctx context.Context, dropboxCfg *cfg.Dropbox, cliOpts *c
 
 	l := ctxzap.Extract(ctx)
 
+	if err := loadCredentialsFile(dropboxCfg); err != nil {
+		return nil, nil, err
+	}
+
 	var opts Option
 	if dropboxCfg.RefreshToken == "" {
 		opts = WithTokenSource(
@@ -134,7 +273,27 @@ func NewLambdaConnector(ctx context.Context, dropboxCfg *cfg.Dropbox, cliOpts *c
 		syncLicenses = cliOpts.WillSyncResourceType(licenseResourceType.Id)
 	}
 
-	cb, err := New(ctx, opts, WithSyncUserLastLogin(dropboxCfg.SyncUserLastLogin), WithSyncLicenses(syncLicenses))
+	cb, err := New(
+		ctx,
+		opts,
+		WithSyncUserLastLogin(dropboxCfg.SyncUserLastLogin),
+		WithLoginAggregation(dropboxCfg.AggregateLoginEvents, time.Duration(dropboxCfg.LoginAggregationHours)*time.Hour),
+		WithSyncResourceChanges(dropboxCfg.SyncResourceChangeEvents),
+		WithSyncGrantEvents(dropboxCfg.SyncGrantEvents),
+		WithSyncFolderActivity(dropboxCfg.SyncFolderActivity),
+		WithSignInInsights(dropboxCfg.SyncSignInInsights, time.Duration(dropboxCfg.SignInInsightDays)*24*time.Hour, dropboxCfg.SsoRequired),
+		WithSyncTwoStepVerification(dropboxCfg.SyncTwoStepVerification),
+		WithTeamPolicies(dropboxCfg.SyncTeamPolicies, time.Duration(dropboxCfg.TeamPolicyInsightDays)*24*time.Hour),
+		WithSyncDeviceActivity(dropboxCfg.SyncDeviceActivity),
+		WithSyncLicenses(syncLicenses),
+		WithStaleInviteAge(time.Duration(dropboxCfg.StaleInviteDays)*24*time.Hour),
+		WithRateLimits(map[dropbox.EndpointClass]dropbox.RateLimit{
+			dropbox.EndpointClassRead:    {RequestsPerMinute: dropboxCfg.ReadRequestsPerMinute},
+			dropbox.EndpointClassWrite:   {RequestsPerMinute: dropboxCfg.WriteRequestsPerMinute},
+			dropbox.EndpointClassTeamLog: {RequestsPerMinute: dropboxCfg.TeamLogRequestsPerMinute},
+		}),
+		WithPageSize(dropboxCfg.PageSize, dropboxCfg.AdaptivePageSize),
+	)
 	if err != nil {
 		l.Error("error creating connector", zap.Error(err))
 		return nil, nil, err
@@ -153,6 +312,9 @@ func New(ctx context.Context, opts ...Option) (*Connector, error) {
 	if c.client == nil {
 		return nil, fmt.Errorf("no client configuration provided")
 	}
+	c.client.SetRateLimits(c.rateLimits)
+	c.client.SetPageSize(c.pageSize, c.adaptivePageSize)
+	c.discoverScopes(ctx)
 	return c, nil
 }
 
@@ -161,65 +323,167 @@ func configure(ctx context.Context, dropboxCfg *cfg.Dropbox) error {
 	if dropboxCfg.AppKey == "" {
 		return fmt.Errorf("app key is required")
 	}
-
-	appSecret := dropboxCfg.AppSecret
-	if dropboxCfg.AppSecret == "" {
-		return fmt.Errorf("app secret is required")
+	if dropboxCfg.CredentialsFile == "" && !dropboxCfg.ConfigurePrintRefreshToken {
+		return fmt.Errorf("--credentials-file is required to store the refresh token, or pass --configure-print-refresh-token to write it to stdout")
+	}
+	if dropboxCfg.CredentialsFile != "" && dropboxCfg.CredentialsPassphrase == "" {
+		return fmt.Errorf("credentials passphrase is required to write the credentials file")
 	}
 
 	client, err := dropbox.NewClient(ctx, dropbox.Config{
 		AppKey:    appKey,
-		AppSecret: appSecret,
+		AppSecret: dropboxCfg.AppSecret,
 		BaseURL:   dropboxCfg.BaseUrl,
 	})
 	if err != nil {
 		return err
 	}
-	code, err := client.Authorize(ctx, appKey, appSecret)
+	auth, err := client.Authorize(ctx, dropbox.AuthorizeOptions{
+		RedirectPort: dropboxCfg.ConfigureRedirectPort,
+		Headless:     dropboxCfg.ConfigureHeadless,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, _, refreshToken, err := client.RequestAccessToken(ctx, auth)
 	if err != nil {
 		return err
 	}
 
-	_, _, refreshToken, err := client.RequestAccessToken(ctx, code)
+	// The refresh token is only ever written to stdout, on request, so it
+	// can be piped into a secret store; it is never logged.
+	if dropboxCfg.CredentialsFile == "" {
+		_, err := fmt.Fprintln(os.Stdout, refreshToken)
+		return err
+	}
+
+	err = writeCredentialsFile(dropboxCfg.CredentialsFile, dropboxCfg.CredentialsPassphrase, storedCredentials{
+		AppKey:       appKey,
+		AppSecret:    dropboxCfg.AppSecret,
+		RefreshToken: refreshToken,
+	})
 	if err != nil {
 		return err
 	}
-	log.Printf("\nrefresh token: %s\n", refreshToken)
+	log.Printf("\ncredentials written to %s\n", dropboxCfg.CredentialsFile)
 	return nil
 }
 
 // ResourceSyncers returns a ResourceSyncer for each resource type that should be synced from the upstream service.
+//
+// Resource types whose read scopes weren't granted are dropped, and those
+// missing the write or delete scopes of a provisioning capability are synced
+// without that capability.
 func (c *Connector) ResourceSyncers(ctx context.Context) []connectorbuilder.ResourceSyncerV2 {
-	return []connectorbuilder.ResourceSyncerV2{
-		newUserBuilder(c.client, c.syncLicenses),
+	var syncers []connectorbuilder.ResourceSyncerV2
+	for _, builder := range c.allResourceSyncers() {
+		if !c.scopesGranted(readScopes(builder.ResourceType(ctx))...) {
+			continue
+		}
+		syncers = append(syncers, c.limitProvisioning(ctx, builder))
+	}
+	return syncers
+}
+
+// syncsFolders reports whether team folders and shared folders are synced,
+// which they are for the features whose events refer to them.
+func (c *Connector) syncsFolders() bool {
+	return c.syncFolderActivity || c.syncResourceChanges
+}
+
+func (c *Connector) allResourceSyncers() []connectorbuilder.ResourceSyncerV2 {
+	syncers := []connectorbuilder.ResourceSyncerV2{
+		// Without events.read, members are synced without their two-step
+		// verification status rather than failing.
+		newUserBuilder(c.client, c.syncLicenses, c.staleInviteAge, c.syncTwoStepVerification && c.scopesGranted("events.read")),
 		newRoleBuilder(c.client),
 		newGroupBuilder(c.client),
 		newLicenseBuilder(),
-		// newFolderBuilder(c.client), // WIP
 		newAppBuilder(),
 	}
+	if c.syncsFolders() {
+		syncers = append(syncers, newFolderBuilder(c.client))
+	}
+	if c.syncSignInInsights {
+		syncers = append(syncers, newSignInInsightBuilder(c.client, c.signInInsightWindow, c.ssoRequired))
+	}
+	if c.syncTeamPolicies {
+		syncers = append(syncers, newTeamBuilder(c.client), newTeamPolicyChangeBuilder(c.client, c.teamPolicyInsightWindow))
+	}
+	return syncers
 }
 
 // EventFeeds returns the login usage event feed when sync-user-last-login is
-// enabled. Dropbox has no last_login field on team members; this is the only
-// way to observe sign-in activity (see team_log/get_events).
+// enabled, the resource change event feed when sync-resource-change-events is,
+// the grant event feed when sync-grant-events is, and the folder activity feed
+// when sync-folder-activity is. Dropbox has no last_login field on team members; the team event log
+// (team_log/get_events) is the only way to observe sign-in activity. Those
+// feeds need events.read; the device activity feed, returned when
+// sync-device-activity is enabled, needs only sessions.list.
 func (c *Connector) EventFeeds(ctx context.Context) []connectorbuilder.EventFeed {
-	if !c.syncUserLastLogin {
-		return nil
+	l := ctxzap.Extract(ctx)
+
+	var feeds []connectorbuilder.EventFeed
+	if c.syncDeviceActivity && c.scopesGranted("sessions.list") {
+		l.Debug("dropbox-connector: sync-device-activity enabled, adding device activity event feed")
+		feeds = append(feeds, newDeviceActivityFeed(c.client))
 	}
 
-	l := ctxzap.Extract(ctx)
-	l.Debug("dropbox-connector: sync-user-last-login enabled, adding login event feed")
+	if !c.scopesGranted("events.read") {
+		return feeds
+	}
 
-	return []connectorbuilder.EventFeed{
-		newLoginEventFeed(c.client),
+	if c.syncUserLastLogin {
+		l.Debug("dropbox-connector: sync-user-last-login enabled, adding login event feed")
+		feeds = append(feeds, newLoginEventFeed(c.client, c.loginAggregation))
 	}
+	if c.syncResourceChanges {
+		l.Debug("dropbox-connector: sync-resource-change-events enabled, adding resource change event feed")
+		feeds = append(feeds, newResourceChangeFeed(c.client, c.syncsFolders() && c.scopesGranted("team_data.member")))
+	}
+	if c.syncGrantEvents {
+		l.Debug("dropbox-connector: sync-grant-events enabled, adding grant event feed")
+		feeds = append(feeds, newGrantEventFeed(c.client))
+	}
+	// Folder activity targets the synced folders, so it needs their scope too.
+	if c.syncFolderActivity && c.scopesGranted("team_data.member") {
+		l.Debug("dropbox-connector: sync-folder-activity enabled, adding folder activity event feed")
+		feeds = append(feeds, newFolderActivityFeed(c.client))
+	}
+	return feeds
 }
 
 // Asset takes an input AssetRef and attempts to fetch it using the connector's authenticated http client
 // It streams a response, always starting with a metadata object, following by chunked payloads for the asset.
+//
+// The only assets this connector references are member profile photos, whose
+// asset ID is the member's team_member_id (see userResource). The photo URL is
+// looked up fresh rather than trusted from the asset ID, so IDs that don't map
+// to a team member with a photo are rejected.
 func (c *Connector) Asset(ctx context.Context, asset *v2.AssetRef) (string, io.ReadCloser, error) {
-	return "", nil, nil
+	teamMemberID := asset.GetId()
+	if teamMemberID == "" {
+		return "", nil, status.Error(codes.InvalidArgument, "asset id is required")
+	}
+
+	member, _, err := c.client.GetMemberInfo(ctx, teamMemberID)
+	if err != nil {
+		return "", nil, fmt.Errorf("dropbox-connector: failed to look up member for asset: %w", err)
+	}
+	if member == nil {
+		return "", nil, status.Errorf(codes.NotFound, "no team member found for asset %s", teamMemberID)
+	}
+	if member.Profile.ProfilePhotoURL == "" {
+		return "", nil, status.Errorf(codes.NotFound, "team member %s has no profile photo", teamMemberID)
+	}
+
+	contentType, body, _, err := c.client.GetProfilePhoto(ctx, member.Profile.ProfilePhotoURL)
+	if err != nil {
+		return "", nil, fmt.Errorf("dropbox-connector: failed to fetch profile photo: %w", err)
+	}
+
+	return contentType, body, nil
 }
 
 // Metadata returns metadata about the connector.
@@ -246,6 +510,51 @@ func (c *Connector) Metadata(ctx context.Context) (*v2.ConnectorMetadata, error)
 
 // Validate is called to ensure that the connector is properly configured. It should exercise any API credentials
 // to be sure that they are valid.
+//
+// It checks that the token is a team token authorized by an active team admin
+// and that the app can read team members. Features whose other scopes are
+// missing are skipped, each reported as a warning annotation.
 func (c *Connector) Validate(ctx context.Context) (annotations.Annotations, error) {
-	return nil, nil
+	l := ctxzap.Extract(ctx)
+
+	team, _, err := c.client.GetTeamInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("dropbox-connector: failed to read team info, check that the credentials are valid "+
+			"and belong to a team-scoped Dropbox app with the team_info.read scope: %w", err)
+	}
+
+	admin, _, err := c.client.GetAuthenticatedAdmin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("dropbox-connector: failed to look up the admin who authorized the app, "+
+			"re-authorize the app as a team admin: %w", err)
+	}
+	if admin.Status.Tag != "active" {
+		return nil, fmt.Errorf("dropbox-connector: the admin who authorized the app (%s) is %s, re-authorize the app as an active team admin",
+			admin.Email, admin.Status.Tag)
+	}
+
+	missing, unverified, err := c.client.CheckScopes(ctx, c.requiredScopes())
+	if err != nil {
+		return nil, fmt.Errorf("dropbox-connector: failed to check granted scopes: %w", err)
+	}
+	c.missingScopes = missing
+	// Every resource type is read through the member list, so without
+	// members.read there's nothing left to sync.
+	if !c.scopesGranted("members.read") {
+		return nil, fmt.Errorf("dropbox-connector: the Dropbox app is missing required scopes: %s. "+
+			"Enable them in the app's Permissions tab, then re-authorize the app (re-run --configure) so the token carries them",
+			strings.Join(missing, ", "))
+	}
+	if len(unverified) > 0 {
+		l.Debug("dropbox-connector: could not verify scopes, the token did not report its granted scopes",
+			zap.Strings("scopes", unverified))
+	}
+
+	l.Debug("dropbox-connector: validated credentials",
+		zap.String("team", team.Name),
+		zap.String("admin", admin.Email),
+	)
+
+	// Anything else missing only disables the features that need it.
+	return c.skippedCapabilityAnnotations(ctx), nil
 }
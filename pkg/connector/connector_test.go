@@ -0,0 +1,363 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	cfg "github.com/conductorone/baton-dropbox/pkg/config"
+	"github.com/conductorone/baton-dropbox/pkg/connector/dropbox"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// newTestConnector points a real dropbox.Client at an httptest server.
+func newTestConnector(t *testing.T, server *httptest.Server) *Connector {
+	t.Helper()
+
+	client, err := dropbox.NewClient(context.Background(), dropbox.Config{BaseURL: server.URL})
+	require.NoError(t, err)
+	client.TokenSource = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "test-token"})
+
+	return &Connector{client: client}
+}
+
+func TestConnector_Asset_StreamsMemberProfilePhoto(t *testing.T) {
+	photo := []byte("\xff\xd8\xff\xe0fake-jpeg")
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/team/members/get_info_v2":
+			var body dropbox.GetMemberInfoBody
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			require.Len(t, body.Members, 1)
+
+			// Dropbox sends the member_info variant's fields next to its tag.
+			item := `{".tag": "id_not_found", "id_not_found": "` + body.Members[0].TeamMemberID + `"}`
+			if body.Members[0].TeamMemberID == "dbmid:1" {
+				item = fmt.Sprintf(`{".tag": "member_info", "profile": {"team_member_id": "dbmid:1", "profile_photo_url": %q}, "roles": []}`,
+					server.URL+"/photos/dbmid-1.jpg")
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"members_info": [` + item + `]}`))
+		case "/photos/dbmid-1.jpg":
+			require.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+			w.Header().Set("Content-Type", "image/jpeg")
+			_, _ = w.Write(photo)
+		default:
+			t.Fatalf("unexpected request path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	c := newTestConnector(t, server)
+
+	contentType, body, err := c.Asset(context.Background(), &v2.AssetRef{Id: "dbmid:1"})
+	require.NoError(t, err)
+	defer body.Close()
+	require.Equal(t, "image/jpeg", contentType)
+
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.Equal(t, photo, data)
+
+	_, _, err = c.Asset(context.Background(), &v2.AssetRef{Id: "dbmid:unknown"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// TestWithRefreshToken_RefreshesExpiredAccessToken verifies that the access
+// token obtained from the refresh token is reused across requests, and that a
+// request rejected with expired_access_token is retried once with a fresh token.
+func TestWithRefreshToken_RefreshesExpiredAccessToken(t *testing.T) {
+	var refreshes int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/oauth2/token":
+			require.NoError(t, r.ParseForm())
+			require.Equal(t, "refresh-token", r.PostForm.Get("refresh_token"))
+			refreshes++
+			require.NoError(t, json.NewEncoder(w).Encode(map[string]any{
+				"access_token": fmt.Sprintf("access-token-%d", refreshes),
+				"expires_in":   14400,
+				"token_type":   "bearer",
+			}))
+		case "/2/team/members/get_info_v2":
+			if r.Header.Get("Authorization") == "Bearer access-token-1" {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"error_summary": "expired_access_token/..", "error": {".tag": "expired_access_token"}}`))
+				return
+			}
+			require.Equal(t, "Bearer access-token-2", r.Header.Get("Authorization"))
+			_, _ = w.Write([]byte(`{"members_info": [{".tag": "member_info", "profile": {"team_member_id": "dbmid:1"}, "roles": []}]}`))
+		default:
+			t.Fatalf("unexpected request path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	c := &Connector{}
+	require.NoError(t, WithRefreshToken(ctx, "app-key", "app-secret", "refresh-token", server.URL)(c))
+	require.Equal(t, 1, refreshes)
+
+	token, err := c.client.TokenSource.Token()
+	require.NoError(t, err)
+	require.Equal(t, "access-token-1", token.AccessToken)
+	require.Equal(t, 1, refreshes, "a token that is not close to expiry should be reused")
+
+	user, _, err := c.client.GetMemberInfo(ctx, "dbmid:1")
+	require.NoError(t, err)
+	require.Equal(t, "dbmid:1", user.Profile.TeamMemberID)
+	require.Equal(t, 2, refreshes)
+}
+
+// TestWithRefreshToken_RefreshesOnceForConcurrentRejections verifies that
+// requests rejected together with the same expired token share one refresh.
+func TestWithRefreshToken_RefreshesOnceForConcurrentRejections(t *testing.T) {
+	const concurrent = 5
+
+	var mu sync.Mutex
+	refreshes := 0
+	var rejected sync.WaitGroup
+	rejected.Add(concurrent)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/oauth2/token":
+			mu.Lock()
+			refreshes++
+			token := fmt.Sprintf("access-token-%d", refreshes)
+			mu.Unlock()
+			require.NoError(t, json.NewEncoder(w).Encode(map[string]any{
+				"access_token": token,
+				"expires_in":   14400,
+				"token_type":   "bearer",
+			}))
+		case "/2/team/members/get_info_v2":
+			if r.Header.Get("Authorization") == "Bearer access-token-1" {
+				// Reject the first token only once every request holds it.
+				rejected.Done()
+				rejected.Wait()
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"error_summary": "expired_access_token/..", "error": {".tag": "expired_access_token"}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"members_info": [{".tag": "member_info", "profile": {"team_member_id": "dbmid:1"}, "roles": []}]}`))
+		default:
+			t.Fatalf("unexpected request path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	c := &Connector{}
+	require.NoError(t, WithRefreshToken(ctx, "app-key", "app-secret", "refresh-token", server.URL)(c))
+
+	var wg sync.WaitGroup
+	for range concurrent {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := c.client.GetMemberInfo(ctx, "dbmid:1")
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, 2, refreshes)
+}
+
+// TestConfigure_RequiresRefreshTokenDestination verifies that --configure
+// won't run an authorization it would have to log the refresh token of.
+func TestConfigure_RequiresRefreshTokenDestination(t *testing.T) {
+	err := configure(context.Background(), &cfg.Dropbox{AppKey: "app-key"})
+	require.ErrorContains(t, err, "--credentials-file")
+}
+
+func TestCredentialsFile_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dropbox.age")
+	creds := storedCredentials{AppKey: "app-key", RefreshToken: "refresh-token"}
+	require.NoError(t, writeCredentialsFile(path, "correct horse", creds))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(raw), "refresh-token")
+
+	_, err = readCredentialsFile(path, "wrong passphrase")
+	require.Error(t, err)
+
+	dropboxCfg := &cfg.Dropbox{CredentialsFile: path, CredentialsPassphrase: "correct horse"}
+	require.NoError(t, loadCredentialsFile(dropboxCfg))
+	require.Equal(t, "app-key", dropboxCfg.AppKey)
+	require.Equal(t, "refresh-token", dropboxCfg.RefreshToken)
+
+	dropboxCfg = &cfg.Dropbox{AppKey: "other-app", CredentialsFile: path, CredentialsPassphrase: "correct horse"}
+	require.ErrorContains(t, loadCredentialsFile(dropboxCfg), "does not match")
+}
+
+// newValidateServer serves the team endpoints Validate calls, rejecting the
+// probe for each scope in missingScopes with a missing_scope error.
+func newValidateServer(t *testing.T, adminStatus string, missingScopes ...string) *httptest.Server {
+	t.Helper()
+
+	probes := map[string]string{
+		"/2/team/members/list_v2":              "members.read",
+		"/2/team/groups/list":                  "groups.read",
+		"/2/team_log/get_events":               "events.read",
+		"/2/team/devices/list_members_devices": "sessions.list",
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if scope, ok := probes[r.URL.Path]; ok && slices.Contains(missingScopes, scope) {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = fmt.Fprintf(w, `{"error_summary": "missing_scope/..", "error": {".tag": "missing_scope", "required_scope": %q}}`, scope)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/2/team/get_info":
+			_, _ = w.Write([]byte(`{"name": "Example Team", "team_id": "dbtid:1"}`))
+		case "/2/team/token/get_authenticated_admin":
+			_, _ = fmt.Fprintf(w, `{"admin_profile": {"team_member_id": "dbmid:admin", "email": "admin@example.com", "status": {".tag": %q}}}`, adminStatus)
+		case "/2/team/members/list_v2", "/2/team/groups/list", "/2/team_log/get_events", "/2/team/devices/list_members_devices":
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			t.Fatalf("unexpected request path: %s", r.URL.Path)
+		}
+	}))
+}
+
+func TestConnector_Validate(t *testing.T) {
+	server := newValidateServer(t, "active")
+	defer server.Close()
+
+	_, err := newTestConnector(t, server).Validate(context.Background())
+	require.NoError(t, err)
+}
+
+func TestConnector_Validate_RejectsInactiveAdmin(t *testing.T) {
+	server := newValidateServer(t, "suspended")
+	defer server.Close()
+
+	_, err := newTestConnector(t, server).Validate(context.Background())
+	require.ErrorContains(t, err, "admin@example.com")
+}
+
+// skippedCapabilityNames returns the capability named by each warning annotation.
+func skippedCapabilityNames(t *testing.T, annos annotations.Annotations) []string {
+	t.Helper()
+
+	var names []string
+	for _, a := range annos {
+		warning := &structpb.Struct{}
+		require.NoError(t, a.UnmarshalTo(warning))
+		names = append(names, warning.GetFields()["capability"].GetStringValue())
+	}
+	return names
+}
+
+// TestConnector_Validate_SkipsCapabilitiesWithProbedMissingScopes verifies
+// that, when the token doesn't list its scopes, read scopes are probed and the
+// features needing the missing ones are skipped with a warning each.
+func TestConnector_Validate_SkipsCapabilitiesWithProbedMissingScopes(t *testing.T) {
+	server := newValidateServer(t, "active", "groups.read", "events.read")
+	defer server.Close()
+
+	c := newTestConnector(t, server)
+	c.syncUserLastLogin = true
+
+	annos, err := c.Validate(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"group sync", "last-login event feed", "list_inactive_members action"}, skippedCapabilityNames(t, annos))
+
+	var syncedTypes []string
+	for _, syncer := range c.ResourceSyncers(context.Background()) {
+		syncedTypes = append(syncedTypes, syncer.ResourceType(context.Background()).GetId())
+	}
+	require.NotContains(t, syncedTypes, groupResourceType.Id)
+	require.Contains(t, syncedTypes, userResourceType.Id)
+	require.Empty(t, c.EventFeeds(context.Background()))
+}
+
+// TestConnector_Validate_SkipsInsightsWithoutEventsRead verifies that the
+// insight syncers are named among the capabilities events.read disables.
+func TestConnector_Validate_SkipsInsightsWithoutEventsRead(t *testing.T) {
+	server := newValidateServer(t, "active", "events.read")
+	defer server.Close()
+
+	c := newTestConnector(t, server)
+	c.syncSignInInsights = true
+	c.syncTeamPolicies = true
+	c.syncGrantEvents = true
+
+	annos, err := c.Validate(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"sign-in insights",
+		"team policy change insights",
+		"grant event feed",
+	}, skippedCapabilityNames(t, annos))
+}
+
+// TestConnector_Validate_DropsProvisioningWithoutWriteScopes verifies that
+// scopes reported on the token are honored, and that a resource type is still
+// synced without just the provisioning capabilities whose scopes are missing.
+func TestConnector_Validate_DropsProvisioningWithoutWriteScopes(t *testing.T) {
+	server := newValidateServer(t, "active")
+	defer server.Close()
+
+	c := newTestConnector(t, server)
+	token := (&oauth2.Token{AccessToken: "test-token"}).WithExtra(map[string]any{
+		"scope": "team_info.read members.read members.write groups.read",
+	})
+	c.client.TokenSource = oauth2.StaticTokenSource(token)
+
+	annos, err := c.Validate(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"user deletion", "group provisioning", "cancel_invitation action"}, skippedCapabilityNames(t, annos))
+
+	for _, syncer := range c.ResourceSyncers(context.Background()) {
+		switch syncer.ResourceType(context.Background()).GetId() {
+		case userResourceType.Id:
+			_, ok := syncer.(connectorbuilder.AccountManagerV2)
+			require.True(t, ok, "account creation only needs members.write")
+			_, ok = syncer.(connectorbuilder.ResourceDeleterLimited)
+			require.False(t, ok, "user deletion should be dropped without members.delete")
+		case groupResourceType.Id:
+			_, ok := syncer.(connectorbuilder.ResourceProvisionerLimited)
+			require.False(t, ok, "group provisioning should be dropped without groups.write")
+		case roleResourceType.Id:
+			_, ok := syncer.(connectorbuilder.ResourceProvisionerLimited)
+			require.True(t, ok, "role provisioning only needs members.write")
+		}
+	}
+}
+
+func TestConnector_Validate_RequiresMembersRead(t *testing.T) {
+	server := newValidateServer(t, "active", "members.read", "groups.read")
+	defer server.Close()
+
+	_, err := newTestConnector(t, server).Validate(context.Background())
+	require.ErrorContains(t, err, "missing required scopes: members.read, groups.read")
+}
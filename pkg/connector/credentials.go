@@ -0,0 +1,140 @@
+package connector
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"filippo.io/age"
+	cfg "github.com/conductorone/baton-dropbox/pkg/config"
+)
+
+// storedCredentials is the plaintext content of a credentials file written by
+// --configure.
+type storedCredentials struct {
+	AppKey       string `json:"app_key"`
+	AppSecret    string `json:"app_secret,omitempty"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// writeCredentialsFile encrypts creds to path with an age scrypt recipient for
+// passphrase. The file is written to a temporary file readable only by the
+// current user and renamed into place, so a failed write never leaves a
+// truncated file behind.
+func writeCredentialsFile(path, passphrase string, creds storedCredentials) error {
+	if passphrase == "" {
+		return fmt.Errorf("dropbox-connector: a passphrase is required to write the credentials file")
+	}
+
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return fmt.Errorf("dropbox-connector: error creating credentials file recipient: %w", err)
+	}
+
+	plaintext, err := json.Marshal(creds)
+	if err != nil {
+		return fmt.Errorf("dropbox-connector: error encoding credentials: %w", err)
+	}
+
+	var ciphertext bytes.Buffer
+	w, err := age.Encrypt(&ciphertext, recipient)
+	if err != nil {
+		return fmt.Errorf("dropbox-connector: error encrypting credentials: %w", err)
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return fmt.Errorf("dropbox-connector: error encrypting credentials: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("dropbox-connector: error encrypting credentials: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("dropbox-connector: error writing credentials file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(ciphertext.Bytes()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("dropbox-connector: error writing credentials file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("dropbox-connector: error writing credentials file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("dropbox-connector: error writing credentials file: %w", err)
+	}
+
+	return nil
+}
+
+// readCredentialsFile decrypts a credentials file written by
+// writeCredentialsFile.
+func readCredentialsFile(path, passphrase string) (*storedCredentials, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("dropbox-connector: a passphrase is required to read the credentials file")
+	}
+
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("dropbox-connector: error creating credentials file identity: %w", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("dropbox-connector: error opening credentials file: %w", err)
+	}
+	defer f.Close()
+
+	r, err := age.Decrypt(f, identity)
+	if err != nil {
+		return nil, fmt.Errorf("dropbox-connector: error decrypting credentials file (is the passphrase correct?): %w", err)
+	}
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("dropbox-connector: error decrypting credentials file: %w", err)
+	}
+
+	var creds storedCredentials
+	if err := json.Unmarshal(plaintext, &creds); err != nil {
+		return nil, fmt.Errorf("dropbox-connector: error decoding credentials file: %w", err)
+	}
+	if creds.RefreshToken == "" {
+		return nil, fmt.Errorf("dropbox-connector: credentials file has no refresh token, re-run with --configure")
+	}
+
+	return &creds, nil
+}
+
+// loadCredentialsFile fills the app key, app secret and refresh token from
+// dropboxCfg's credentials file, if one is configured. Values given directly
+// in the config take precedence; an app key that disagrees with the file is
+// rejected, since the refresh token only works for the app that issued it.
+func loadCredentialsFile(dropboxCfg *cfg.Dropbox) error {
+	if dropboxCfg.CredentialsFile == "" {
+		return nil
+	}
+
+	creds, err := readCredentialsFile(dropboxCfg.CredentialsFile, dropboxCfg.CredentialsPassphrase)
+	if err != nil {
+		return err
+	}
+
+	if dropboxCfg.AppKey != "" && creds.AppKey != "" && dropboxCfg.AppKey != creds.AppKey {
+		return fmt.Errorf("dropbox-connector: app key does not match the app key in the credentials file")
+	}
+	if dropboxCfg.AppKey == "" {
+		dropboxCfg.AppKey = creds.AppKey
+	}
+	if dropboxCfg.AppSecret == "" {
+		dropboxCfg.AppSecret = creds.AppSecret
+	}
+	if dropboxCfg.RefreshToken == "" {
+		dropboxCfg.RefreshToken = creds.RefreshToken
+	}
+
+	return nil
+}
@@ -0,0 +1,214 @@
+package connector
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/conductorone/baton-dropbox/pkg/connector/dropbox"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const deviceActivityEventFeedID = "dropbox_device_activity_feed"
+
+// deviceActivityFeed emits UsageEvents on the Dropbox app for each member's
+// most recent device activity, the newest updated time of their web sessions,
+// desktop clients and mobile clients (team/devices/list_members_devices). It
+// is a last-activity source for teams that don't grant events.read to the
+// app: it needs only the sessions.list scope. Gated behind the
+// sync-device-activity config flag.
+//
+// The devices list is a snapshot, not a log, so each pass reads all of it and
+// reports the members whose activity is newer than the previous pass's
+// newest. Activity Dropbox records late, older than that, is not reported;
+// the member's next activity is.
+type deviceActivityFeed struct {
+	client *dropbox.Client
+}
+
+func newDeviceActivityFeed(client *dropbox.Client) *deviceActivityFeed {
+	return &deviceActivityFeed{client: client}
+}
+
+func (f *deviceActivityFeed) EventFeedMetadata(_ context.Context) *v2.EventFeedMetadata {
+	return &v2.EventFeedMetadata{
+		Id: deviceActivityEventFeedID,
+		SupportedEventTypes: []v2.EventType{
+			v2.EventType_EVENT_TYPE_USAGE,
+		},
+	}
+}
+
+// deviceActivityPageToken is the cursor persisted between ListEvents calls:
+// the Dropbox cursor of the pass in progress and, as in loginEventPageToken,
+// the pass's start and the newest activity seen in it.
+type deviceActivityPageToken struct {
+	LatestActivitySeen string `json:"latest_activity_seen,omitempty"`
+	NextPageToken      string `json:"next_page_token,omitempty"`
+	StartAt            string `json:"start_at,omitempty"`
+}
+
+func unmarshalDeviceActivityPageToken(token *pagination.StreamToken, defaultStart *timestamppb.Timestamp) (*deviceActivityPageToken, error) {
+	pt := &deviceActivityPageToken{}
+	if token != nil && token.Cursor != "" {
+		data, err := base64.StdEncoding.DecodeString(token.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, pt); err != nil {
+			return nil, err
+		}
+	}
+
+	if pt.StartAt == "" {
+		if defaultStart == nil {
+			defaultStart = timestamppb.New(time.Now().Add(-defaultCatchUpWindow))
+		}
+		pt.StartAt = defaultStart.AsTime().UTC().Format(dropbox.TimestampFormat)
+	}
+	if pt.LatestActivitySeen == "" {
+		pt.LatestActivitySeen = pt.StartAt
+	}
+	return pt, nil
+}
+
+// finishPage records the cursor of the next page or, once the devices list is
+// drained, starts the next pass at the newest activity seen.
+func (pt *deviceActivityPageToken) finishPage(payload *dropbox.ListMembersDevicesPayload) {
+	pt.NextPageToken = payload.Cursor
+	if !payload.HasMore {
+		pt.StartAt = pt.LatestActivitySeen
+		pt.LatestActivitySeen = ""
+		pt.NextPageToken = ""
+	}
+}
+
+func (pt *deviceActivityPageToken) marshal() (string, error) {
+	data, err := json.Marshal(pt)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+func (f *deviceActivityFeed) ListEvents(
+	ctx context.Context,
+	startAt *timestamppb.Timestamp,
+	pToken *pagination.StreamToken,
+) ([]*v2.Event, *pagination.StreamState, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	cursor, err := unmarshalDeviceActivityPageToken(pToken, startAt)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("dropbox-connector: failed to unmarshal device activity page token: %w", err)
+	}
+
+	payload, rateLimitData, err := f.client.ListMembersDevices(ctx, cursor.NextPageToken)
+
+	var outAnnotations annotations.Annotations
+	outAnnotations.WithRateLimiting(rateLimitData)
+
+	if err != nil {
+		return nil, nil, outAnnotations, fmt.Errorf("dropbox-connector: failed to list members' devices: %w", err)
+	}
+
+	start, err := time.Parse(dropbox.TimestampFormat, cursor.StartAt)
+	if err != nil {
+		l.Debug("dropbox-connector: failed to parse device activity start time, using default catch-up window", zap.Error(err))
+		start = time.Now().Add(-defaultCatchUpWindow)
+	}
+	latestActivity, err := time.Parse(dropbox.TimestampFormat, cursor.LatestActivitySeen)
+	if err != nil {
+		latestActivity = start
+	}
+
+	events := make([]*v2.Event, 0, len(payload.Devices))
+	for _, member := range payload.Devices {
+		if member.TeamMemberID == "" {
+			continue
+		}
+		lastActive, ok := lastDeviceActivity(ctx, member)
+		if !ok || !lastActive.After(start) {
+			continue
+		}
+		if lastActive.After(latestActivity) {
+			latestActivity = lastActive
+			cursor.LatestActivitySeen = lastActive.UTC().Format(dropbox.TimestampFormat)
+		}
+
+		events = append(events, &v2.Event{
+			// The ID names the member and the activity time, so a pass that
+			// re-reads unchanged activity yields the same event.
+			Id:         fmt.Sprintf("device-activity-%s-%s", member.TeamMemberID, lastActive.UTC().Format(dropbox.TimestampFormat)),
+			OccurredAt: timestamppb.New(lastActive),
+			Event: &v2.Event_UsageEvent{
+				UsageEvent: &v2.UsageEvent{
+					TargetResource: &v2.Resource{
+						Id: &v2.ResourceId{
+							ResourceType: appResourceType.Id,
+							Resource:     dropboxAppResourceID,
+						},
+						DisplayName: dropboxAppDisplayName,
+					},
+					// The devices list carries only team member IDs, which are the
+					// user resources' IDs.
+					ActorResource: &v2.Resource{
+						Id: &v2.ResourceId{
+							ResourceType: userResourceType.Id,
+							Resource:     member.TeamMemberID,
+						},
+					},
+				},
+			},
+		})
+	}
+
+	cursor.finishPage(payload)
+
+	cursorToken, err := cursor.marshal()
+	if err != nil {
+		return nil, nil, outAnnotations, fmt.Errorf("dropbox-connector: failed to marshal device activity cursor: %w", err)
+	}
+
+	return events, &pagination.StreamState{
+		Cursor:  cursorToken,
+		HasMore: payload.HasMore,
+	}, outAnnotations, nil
+}
+
+// lastDeviceActivity returns the newest updated time, or created time when
+// Dropbox reports no update, across a member's devices. It returns false for
+// members with no device reporting either.
+func lastDeviceActivity(ctx context.Context, member dropbox.MemberDevices) (time.Time, bool) {
+	l := ctxzap.Extract(ctx)
+
+	var newest time.Time
+	for _, sessions := range [][]dropbox.DeviceSession{member.WebSessions, member.DesktopClients, member.MobileClients} {
+		for _, s := range sessions {
+			timestamp := s.Updated
+			if timestamp == "" {
+				timestamp = s.Created
+			}
+			if timestamp == "" {
+				continue
+			}
+			activeAt, err := time.Parse(dropbox.TimestampFormat, timestamp)
+			if err != nil {
+				l.Debug("dropbox-connector: skipping device session with unparseable timestamp",
+					zap.String("session_id", s.SessionID), zap.String("timestamp", timestamp), zap.Error(err))
+				continue
+			}
+			if activeAt.After(newest) {
+				newest = activeAt
+			}
+		}
+	}
+	return newest, !newest.IsZero()
+}
@@ -0,0 +1,129 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-dropbox/pkg/connector/dropbox"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestDeviceActivityFeed_ListEvents(t *testing.T) {
+	firstPage := dropbox.ListMembersDevicesPayload{
+		Devices: []dropbox.MemberDevices{
+			{
+				TeamMemberID: "dbmid:alice",
+				WebSessions:  []dropbox.DeviceSession{{SessionID: "web:1", Updated: "2024-01-02T09:00:00Z"}},
+				DesktopClients: []dropbox.DeviceSession{
+					{SessionID: "desktop:1", Created: "2023-12-01T00:00:00Z", Updated: "2024-01-03T10:00:00Z"},
+				},
+			},
+			{
+				// Idle since before the sync window.
+				TeamMemberID:  "dbmid:bob",
+				MobileClients: []dropbox.DeviceSession{{SessionID: "mobile:1", Updated: "2023-11-01T00:00:00Z"}},
+			},
+		},
+		Cursor:  "page-2-cursor",
+		HasMore: true,
+	}
+	secondPage := dropbox.ListMembersDevicesPayload{
+		Devices: []dropbox.MemberDevices{
+			{
+				// No updated time; created is used.
+				TeamMemberID:  "dbmid:carol",
+				MobileClients: []dropbox.DeviceSession{{SessionID: "mobile:2", Created: "2024-01-04T08:00:00Z"}},
+			},
+			{TeamMemberID: "dbmid:dave"},
+		},
+	}
+
+	var cursors []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/2/team/devices/list_members_devices", r.URL.Path)
+
+		var body dropbox.ListMembersDevicesBody
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.True(t, body.IncludeWebSessions && body.IncludeDesktopClients && body.IncludeMobileClients)
+		cursors = append(cursors, body.Cursor)
+
+		w.Header().Set("Content-Type", "application/json")
+		if body.Cursor == "page-2-cursor" {
+			require.NoError(t, json.NewEncoder(w).Encode(secondPage))
+			return
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(firstPage))
+	}))
+	defer server.Close()
+
+	feed := newDeviceActivityFeed(newTestConnector(t, server).client)
+	startAt := timestamppb.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	events, state, _, err := feed.ListEvents(context.Background(), startAt, nil)
+	require.NoError(t, err)
+	require.True(t, state.HasMore)
+	require.Len(t, events, 1)
+	require.Equal(t, "device-activity-dbmid:alice-2024-01-03T10:00:00Z", events[0].GetId())
+	require.Equal(t, mustParseDropboxTime(t, "2024-01-03T10:00:00Z"), events[0].GetOccurredAt().AsTime())
+	usage := events[0].GetUsageEvent()
+	require.Equal(t, &v2.ResourceId{ResourceType: appResourceType.Id, Resource: dropboxAppResourceID}, usage.GetTargetResource().GetId())
+	require.Equal(t, &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "dbmid:alice"}, usage.GetActorResource().GetId())
+
+	events, state, _, err = feed.ListEvents(context.Background(), startAt, &pagination.StreamToken{Cursor: state.Cursor})
+	require.NoError(t, err)
+	require.False(t, state.HasMore)
+	require.Len(t, events, 1)
+	require.Equal(t, "device-activity-dbmid:carol-2024-01-04T08:00:00Z", events[0].GetId())
+
+	// The next pass starts at the newest activity seen, so unchanged activity
+	// isn't reported again.
+	cursor, err := unmarshalDeviceActivityPageToken(&pagination.StreamToken{Cursor: state.Cursor}, startAt)
+	require.NoError(t, err)
+	require.Equal(t, "2024-01-04T08:00:00Z", cursor.StartAt)
+	require.Empty(t, cursor.NextPageToken)
+
+	events, _, _, err = feed.ListEvents(context.Background(), startAt, &pagination.StreamToken{Cursor: state.Cursor})
+	require.NoError(t, err)
+	require.Empty(t, events)
+	require.Equal(t, []string{"", "page-2-cursor", ""}, cursors)
+}
+
+// TestConnector_EventFeeds_DeviceActivityWithoutEventsRead verifies that the
+// device activity feed is offered to apps granted sessions.list but not
+// events.read, while the audit log feeds are skipped.
+func TestConnector_EventFeeds_DeviceActivityWithoutEventsRead(t *testing.T) {
+	server := newValidateServer(t, "active", "events.read")
+	defer server.Close()
+
+	c := newTestConnector(t, server)
+	c.syncUserLastLogin = true
+	c.syncDeviceActivity = true
+
+	annos, err := c.Validate(context.Background())
+	require.NoError(t, err)
+	require.NotContains(t, skippedCapabilityNames(t, annos), "device activity event feed")
+
+	feeds := c.EventFeeds(context.Background())
+	require.Len(t, feeds, 1)
+	require.Equal(t, deviceActivityEventFeedID, feeds[0].EventFeedMetadata(context.Background()).GetId())
+}
+
+func TestConnector_EventFeeds_SkipsDeviceActivityWithoutSessionsList(t *testing.T) {
+	server := newValidateServer(t, "active", "sessions.list")
+	defer server.Close()
+
+	c := newTestConnector(t, server)
+	c.syncDeviceActivity = true
+
+	annos, err := c.Validate(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"device activity event feed"}, skippedCapabilityNames(t, annos))
+	require.Empty(t, c.EventFeeds(context.Background()))
+}
@@ -3,40 +3,52 @@ package dropbox
 import (
 	"bufio"
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"golang.org/x/oauth2"
 	"golang.org/x/term"
 )
 
-// returns access token and expiry time
+// returns an access token whose expiry is set and whose "scope" extra holds the
+// space-separated scopes granted to the app, if Dropbox reported them
 //
 //	curl https://api.dropbox.com/oauth2/token \
 //	    -d grant_type=refresh_token \
 //	    -d refresh_token=<refresh_token> \
 //	    -d client_id=<app_key> \
 //	    -d client_secret=<app_secret>
-func (c *Client) RequestAccessTokenUsingRefreshToken(ctx context.Context) (string, *time.Time, error) {
+func (c *Client) RequestAccessTokenUsingRefreshToken(ctx context.Context) (*oauth2.Token, error) {
 	if c.config.RefreshToken == "" {
-		return "", nil, fmt.Errorf("dropbox-connector: refresh token is empty, run with --configure flag to get a refresh token")
+		return nil, fmt.Errorf("dropbox-connector: refresh token is empty, run with --configure flag to get a refresh token")
 	}
 	grantType := "refresh_token"
 
 	form := url.Values{}
 	form.Set("client_id", c.config.AppKey)
-	form.Set("client_secret", c.config.AppSecret)
+	// Refresh tokens issued through the PKCE flow don't need the app secret.
+	if c.config.AppSecret != "" {
+		form.Set("client_secret", c.config.AppSecret)
+	}
 	form.Set("refresh_token", c.config.RefreshToken)
 	form.Set("grant_type", grantType)
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url("/oauth2/token"), strings.NewReader(form.Encode()))
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
@@ -44,39 +56,195 @@ func (c *Client) RequestAccessTokenUsingRefreshToken(ctx context.Context) (strin
 		AccessToken string `json:"access_token"`
 		ExpiresIn   int    `json:"expires_in"`
 		TokenType   string `json:"token_type"`
+		Scope       string `json:"scope"`
 	}
 	res, err := c.wrapper.Do(req,
 		uhttp.WithJSONResponse(&target),
 	)
 	if err != nil {
-		return "", nil, err
+		return nil, fmt.Errorf("error getting access token: %w", wrapAPIError(res, err))
 	}
-
 	defer res.Body.Close()
-	if res.StatusCode != http.StatusOK {
-		logBody(ctx, res)
-		return "", nil, fmt.Errorf("error getting access token: %s", res.Status)
+
+	token := &oauth2.Token{
+		AccessToken: target.AccessToken,
+		TokenType:   "Bearer",
+		Expiry:      time.Now().Add(time.Duration(target.ExpiresIn) * time.Second),
+	}
+	return token.WithExtra(map[string]any{"scope": target.Scope}), nil
+}
+
+// authorizeTimeout bounds how long Authorize waits for the browser to hit the
+// loopback redirect before giving up.
+const authorizeTimeout = 5 * time.Minute
+
+// DefaultRedirectPort is the loopback port used for the OAuth redirect when none
+// is configured. Dropbox matches redirect URIs exactly, so
+// http://127.0.0.1:<port>/oauth2/callback must be registered on the app.
+const DefaultRedirectPort = 53682
+
+// RedirectURI returns the loopback redirect URI for port.
+func RedirectURI(port int) string {
+	return fmt.Sprintf("http://127.0.0.1:%d/oauth2/callback", port)
+}
+
+// AuthorizeOptions controls how Authorize obtains the authorization code.
+type AuthorizeOptions struct {
+	// RedirectPort is the loopback port the redirect listener binds to.
+	RedirectPort int
+	// Headless skips the redirect listener; the user opens the link on any
+	// machine and pastes the code Dropbox displays. Requires a terminal.
+	Headless bool
+}
+
+// AuthorizationCode is the result of the user approving the app, carrying
+// everything RequestAccessToken needs to redeem it.
+type AuthorizationCode struct {
+	Code string
+	// CodeVerifier is the PKCE secret whose S256 challenge was sent with the
+	// authorization request.
+	CodeVerifier string
+	// RedirectURI must be repeated on the token request when one was used to
+	// obtain the code; it is empty for the paste-code flow.
+	RedirectURI string
+}
+
+// Authorize runs the OAuth 2 authorization code flow with PKCE, so the app
+// secret is not needed to redeem the code. By default it listens on a
+// loopback redirect URI and receives the code from the browser, checking the
+// returned state; with opts.Headless it falls back to asking for the code on
+// stdin.
+func (c *Client) Authorize(ctx context.Context, opts AuthorizeOptions) (*AuthorizationCode, error) {
+	verifier, err := randomURLSafeString(32)
+	if err != nil {
+		return nil, fmt.Errorf("dropbox-connector: error generating PKCE verifier: %w", err)
+	}
+	state, err := randomURLSafeString(16)
+	if err != nil {
+		return nil, fmt.Errorf("dropbox-connector: error generating OAuth state: %w", err)
+	}
+
+	if opts.Headless {
+		isTTY := term.IsTerminal(int(os.Stdout.Fd())) //nolint:gosec // stdout fd is always a small non-negative value, cannot overflow int
+		if !isTTY {
+			return nil, fmt.Errorf("dropbox-connector: non-interactive mode not supported. Pass a refresh token as an argument ")
+		}
+
+		log.Printf("\nOpen this link in your browser: %s", c.authorizeURL(state, verifier, ""))
+		log.Printf("\nPaste the code: ")
+
+		code, err := readCode(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		return &AuthorizationCode{Code: code, CodeVerifier: verifier}, nil
+	}
+
+	port := opts.RedirectPort
+	if port == 0 {
+		port = DefaultRedirectPort
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return nil, fmt.Errorf("dropbox-connector: error starting redirect listener on port %d (use --configure-headless to paste the code instead): %w", port, err)
+	}
+
+	redirectURI := RedirectURI(port)
+	log.Printf("\nOpen this link in your browser: %s", c.authorizeURL(state, verifier, redirectURI))
+	log.Printf("\nWaiting for Dropbox to redirect to %s ...", redirectURI)
+
+	ctx, cancel := context.WithTimeout(ctx, authorizeTimeout)
+	defer cancel()
+
+	code, err := awaitRedirect(ctx, listener, state)
+	if err != nil {
+		return nil, err
+	}
+	return &AuthorizationCode{Code: code, CodeVerifier: verifier, RedirectURI: redirectURI}, nil
+}
+
+// authorizeURL builds the authorization link. redirectURI is omitted for the
+// paste-code flow, in which case Dropbox shows the code to the user.
+func (c *Client) authorizeURL(state, verifier, redirectURI string) string {
+	challenge := sha256.Sum256([]byte(verifier))
+
+	query := url.Values{}
+	query.Set("client_id", c.config.AppKey)
+	query.Set("response_type", "code")
+	query.Set("token_access_type", "offline")
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	query.Set("state", state)
+	if redirectURI != "" {
+		query.Set("redirect_uri", redirectURI)
 	}
 
-	expiresIn := time.Now().Add(time.Duration(target.ExpiresIn) * time.Second)
-	return target.AccessToken, &expiresIn, nil
+	return AuthURL + "?" + query.Encode()
 }
 
-func (c *Client) Authorize(ctx context.Context, appKey, appSecret string) (string, error) {
-	isTTY := term.IsTerminal(int(os.Stdout.Fd())) //nolint:gosec // stdout fd is always a small non-negative value, cannot overflow int
-	if !isTTY {
-		return "", fmt.Errorf("dropbox-connector: non-interactive mode not supported. Pass a refresh token as an argument ")
+// awaitRedirect serves the loopback redirect on listener until a request with
+// the expected state arrives, and returns its authorization code. Requests with
+// a mismatched state are rejected and ignored, so a stray or forged redirect
+// can't inject a code.
+func awaitRedirect(ctx context.Context, listener net.Listener, state string) (string, error) {
+	type result struct {
+		code string
+		err  error
 	}
+	results := make(chan result, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/callback", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(state)) != 1 {
+			http.Error(w, "Invalid OAuth state.", http.StatusBadRequest)
+			return
+		}
+
+		var res result
+		switch {
+		case query.Get("error") != "":
+			res.err = fmt.Errorf("dropbox-connector: authorization failed: %s: %s", query.Get("error"), query.Get("error_description"))
+			http.Error(w, "Authorization failed, you can close this window.", http.StatusBadRequest)
+		case query.Get("code") == "":
+			res.err = fmt.Errorf("dropbox-connector: authorization redirect did not include a code")
+			http.Error(w, "Authorization failed, you can close this window.", http.StatusBadRequest)
+		default:
+			res.code = query.Get("code")
+			_, _ = fmt.Fprintln(w, "Authorization complete, you can close this window.")
+		}
 
-	url := fmt.Sprintf("%s?client_id=%s&token_access_type=offline&response_type=code", AuthURL, appKey)
-	log.Printf("\nOpen this link in your browser: %s", url)
-	log.Printf("\nPaste the code: ")
+		select {
+		case results <- res:
+		default:
+		}
+	})
 
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer func() {
+		// Shut down gracefully so the browser still receives the response
+		// written by the handler that delivered the code.
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	select {
+	case res := <-results:
+		return res.code, res.err
+	case <-ctx.Done():
+		return "", fmt.Errorf("dropbox-connector: timed out waiting for the authorization redirect: %w", ctx.Err())
+	}
+}
+
+func readCode(r io.Reader) (string, error) {
 	var code string
-	scanner := bufio.NewScanner(os.Stdin)
+	scanner := bufio.NewScanner(r)
 	if scanner.Scan() {
-		code = scanner.Text()
-		code = strings.TrimSpace(code)
+		code = strings.TrimSpace(scanner.Text())
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -86,14 +254,31 @@ func (c *Client) Authorize(ctx context.Context, appKey, appSecret string) (strin
 	return code, nil
 }
 
-func (c *Client) RequestAccessToken(ctx context.Context, code string) (string, *time.Time, string, error) {
+func randomURLSafeString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// RequestAccessToken redeems an authorization code, returning the access
+// token, its expiry and the refresh token. The app secret is sent only if
+// configured; the PKCE verifier authenticates the request otherwise.
+func (c *Client) RequestAccessToken(ctx context.Context, auth *AuthorizationCode) (string, *time.Time, string, error) {
 	grantType := "authorization_code"
 
 	form := url.Values{}
 	form.Set("grant_type", grantType)
 	form.Set("client_id", c.config.AppKey)
-	form.Set("client_secret", c.config.AppSecret)
-	form.Set("code", code)
+	if c.config.AppSecret != "" {
+		form.Set("client_secret", c.config.AppSecret)
+	}
+	form.Set("code", auth.Code)
+	form.Set("code_verifier", auth.CodeVerifier)
+	if auth.RedirectURI != "" {
+		form.Set("redirect_uri", auth.RedirectURI)
+	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url("/oauth2/token"), strings.NewReader(form.Encode()))
 	if err != nil {
@@ -111,14 +296,9 @@ func (c *Client) RequestAccessToken(ctx context.Context, code string) (string, *
 		uhttp.WithJSONResponse(&target),
 	)
 	if err != nil {
-		return "", nil, "", err
+		return "", nil, "", fmt.Errorf("error getting access token: %w", wrapAPIError(res, err))
 	}
-
 	defer res.Body.Close()
-	if res.StatusCode != http.StatusOK {
-		logBody(ctx, res)
-		return "", nil, "", fmt.Errorf("error getting access token: %s", res.Status)
-	}
 
 	accessTokenexpiresIn := time.Now().Add(time.Duration(target.ExpiresIn) * time.Second)
 	return target.AccessToken, &accessTokenexpiresIn, target.RefreshToken, nil
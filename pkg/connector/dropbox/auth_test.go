@@ -0,0 +1,116 @@
+package dropbox
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthorizeURL_UsesPKCEAndState(t *testing.T) {
+	c := &Client{config: Config{AppKey: "app-key"}}
+
+	authURL, err := url.Parse(c.authorizeURL("state-1", "verifier-1", RedirectURI(8080)))
+	require.NoError(t, err)
+
+	challenge := sha256.Sum256([]byte("verifier-1"))
+	query := authURL.Query()
+	require.Equal(t, "app-key", query.Get("client_id"))
+	require.Equal(t, "offline", query.Get("token_access_type"))
+	require.Equal(t, "S256", query.Get("code_challenge_method"))
+	require.Equal(t, base64.RawURLEncoding.EncodeToString(challenge[:]), query.Get("code_challenge"))
+	require.Equal(t, "state-1", query.Get("state"))
+	require.Equal(t, "http://127.0.0.1:8080/oauth2/callback", query.Get("redirect_uri"))
+}
+
+// TestAwaitRedirect_ValidatesState verifies that a redirect carrying the wrong
+// state is rejected without ending the flow, and the first one with the
+// expected state yields its code.
+func TestAwaitRedirect_ValidatesState(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	callback := "http://" + listener.Addr().String() + "/oauth2/callback"
+
+	type result struct {
+		code string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		code, err := awaitRedirect(context.Background(), listener, "expected-state")
+		done <- result{code, err}
+	}()
+
+	res, err := http.Get(callback + "?state=forged&code=evil")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	res, err = http.Get(callback + "?state=expected-state&code=the-code")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	got := <-done
+	require.NoError(t, got.err)
+	require.Equal(t, "the-code", got.code)
+}
+
+func TestAwaitRedirect_ReportsAuthorizationError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := awaitRedirect(context.Background(), listener, "s")
+		done <- err
+	}()
+
+	res, err := http.Get("http://" + listener.Addr().String() + "/oauth2/callback?state=s&error=access_denied")
+	require.NoError(t, err)
+	res.Body.Close()
+
+	require.ErrorContains(t, <-done, "access_denied")
+}
+
+// TestRequestAccessToken_SendsVerifierWithoutSecret verifies that a code from
+// the PKCE flow is redeemed with its verifier and redirect URI, and that no
+// client_secret is sent when none is configured.
+func TestRequestAccessToken_SendsVerifierWithoutSecret(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/oauth2/token", r.URL.Path)
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "authorization_code", r.PostForm.Get("grant_type"))
+		require.Equal(t, "the-code", r.PostForm.Get("code"))
+		require.Equal(t, "the-verifier", r.PostForm.Get("code_verifier"))
+		require.Equal(t, RedirectURI(8080), r.PostForm.Get("redirect_uri"))
+		require.False(t, r.PostForm.Has("client_secret"))
+
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  "access",
+			"refresh_token": "refresh",
+			"expires_in":    14400,
+			"token_type":    "bearer",
+		}))
+	}))
+	defer server.Close()
+
+	c, err := NewClient(context.Background(), Config{AppKey: "app-key", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	_, _, refreshToken, err := c.RequestAccessToken(context.Background(), &AuthorizationCode{
+		Code:         "the-code",
+		CodeVerifier: "the-verifier",
+		RedirectURI:  RedirectURI(8080),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "refresh", refreshToken)
+}
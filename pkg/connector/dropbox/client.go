@@ -2,8 +2,12 @@ package dropbox
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
+	"sync"
+	"time"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
@@ -18,6 +22,11 @@ type Client struct {
 	config      Config
 	TokenSource oauth2.TokenSource
 	baseURL     string
+
+	throttle   *throttle
+	maxRetries int
+	retryBase  time.Duration
+	pageSizers map[listing]*pageSizer
 }
 
 type Config struct {
@@ -25,6 +34,13 @@ type Config struct {
 	AppSecret    string
 	RefreshToken string
 	BaseURL      string
+	// RateLimits overrides DefaultRateLimits per endpoint class.
+	RateLimits map[EndpointClass]RateLimit
+	// PageSize is the member and group page size; DefaultPageSize if zero.
+	PageSize int
+	// AdaptivePageSize grows page sizes while responses are fast and shrinks
+	// them after timeouts and rate limits.
+	AdaptivePageSize bool
 }
 
 func NewClient(ctx context.Context, config Config) (*Client, error) {
@@ -50,10 +66,14 @@ func NewClient(ctx context.Context, config Config) (*Client, error) {
 	}
 
 	client := &Client{
-		wrapper: wrapper,
-		config:  config,
-		baseURL: baseURL,
+		wrapper:    wrapper,
+		config:     config,
+		baseURL:    baseURL,
+		throttle:   newThrottle(config.RateLimits),
+		maxRetries: defaultMaxRetries,
+		retryBase:  retryBaseDelay,
 	}
+	client.SetPageSize(config.PageSize, config.AdaptivePageSize)
 	return client, nil
 }
 
@@ -62,21 +82,168 @@ func (c *Client) url(path string) string {
 	return c.baseURL + path
 }
 
+// RequestOption customizes a single API request, e.g. to act as a team member.
+type RequestOption func(header map[string]string)
+
+// AsMember makes a team-scoped request act on behalf of the given team member,
+// as required by user-level endpoints (files, sharing, users/*) called with a
+// team token.
+func AsMember(teamMemberID string) RequestOption {
+	return func(header map[string]string) {
+		header["Dropbox-API-Select-User"] = teamMemberID
+	}
+}
+
+// AsAdmin makes a team-scoped request act as the given team admin. Unlike
+// AsMember, it also grants access to team-owned content such as team folders.
+func AsAdmin(teamMemberID string) RequestOption {
+	return func(header map[string]string) {
+		header["Dropbox-API-Select-Admin"] = teamMemberID
+	}
+}
+
+// WithRootNamespace resolves paths relative to the namespace rootNamespaceID,
+// which must be the user's root namespace (e.g. the team space root).
+// Docs: https://www.dropbox.com/developers/reference/path-root-header-modes
+func WithRootNamespace(rootNamespaceID string) RequestOption {
+	return withPathRoot(map[string]string{".tag": "root", "root": rootNamespaceID})
+}
+
+// WithNamespace resolves paths relative to namespaceID, any namespace the
+// user has access to (e.g. a shared or team folder).
+func WithNamespace(namespaceID string) RequestOption {
+	return withPathRoot(map[string]string{".tag": "namespace_id", "namespace_id": namespaceID})
+}
+
+func withPathRoot(pathRoot map[string]string) RequestOption {
+	// Marshaling a map of strings can't fail.
+	value, _ := json.Marshal(pathRoot)
+	return func(header map[string]string) {
+		header["Dropbox-API-Path-Root"] = string(value)
+	}
+}
+
 // doRequest executes an HTTP request and decodes the response into the provided result.
 // It handles authentication, headers, rate limiting, and error handling consistently.
+//
+// Requests wait for a token from their endpoint class's bucket (see
+// EndpointClass). Requests Dropbox throttles are retried with backoff and
+// jitter, never sooner than its Retry-After. Each attempt of a write holds
+// its namespace's lock, so writes to a namespace are serialized and don't fail
+// as too_many_write_operations with each other; the lock is released while a
+// throttled write waits to retry, so it doesn't hold up the namespace's other
+// writes.
+//
+// If Dropbox rejects the access token as expired_access_token (e.g. it was
+// revoked or expired early) and the token source can refresh, the token is
+// refreshed once and the request retried.
 func (c *Client) doRequest(
 	ctx context.Context,
 	endpointURL string,
 	method string,
 	result any,
 	body any,
+	opts ...RequestOption,
 ) (annotations.Annotations, error) {
 	l := ctxzap.Extract(ctx)
 
-	token, err := c.TokenSource.Token()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get token: %w", err)
+	class := EndpointClassRead
+	var sizer *pageSizer
+	if parsedURL, err := url.Parse(endpointURL); err == nil {
+		class = endpointClass(parsedURL.Path)
+		sizer = c.pageSizers[listingOf(parsedURL.Path)]
+	}
+
+	var namespaceLock *sync.Mutex
+	if class == EndpointClassWrite {
+		namespaceLock = c.throttle.namespaceLock(requestNamespace(requestHeader(opts)))
+	}
+
+	refreshed := false
+
+	for attempt := 1; ; attempt++ {
+		if err := c.throttle.wait(ctx, class); err != nil {
+			return nil, fmt.Errorf("waiting for rate limit: %w", err)
+		}
+
+		token, err := c.TokenSource.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get token: %w", err)
+		}
+
+		if namespaceLock != nil {
+			namespaceLock.Lock()
+		}
+		start := time.Now()
+		annos, err := c.doRequestOnce(ctx, token, endpointURL, method, result, body, opts)
+		if namespaceLock != nil {
+			namespaceLock.Unlock()
+		}
+		if sizer != nil {
+			sizer.observe(time.Since(start), err)
+		}
+		if err == nil {
+			rateLimit := getRateLimitFromAnnos(annos)
+			if rateLimit == nil {
+				rateLimit = &v2.RateLimitDescription{}
+			}
+			c.throttle.describe(class, rateLimit)
+			annos = annotations.Annotations{}
+			annos.WithRateLimiting(rateLimit)
+			return annos, nil
+		}
+
+		if HasErrorTag(err, "expired_access_token") && !refreshed {
+			refresher, ok := c.TokenSource.(tokenRefresher)
+			if !ok {
+				return annos, err
+			}
+			l.Debug("access token expired, refreshing and retrying request", zap.String("url", endpointURL))
+			if refreshErr := refresher.ForceRefresh(ctx, token); refreshErr != nil {
+				return annos, fmt.Errorf("%w (refreshing access token failed: %w)", err, refreshErr)
+			}
+			refreshed = true
+			continue
+		}
+
+		delay, retry := c.retryDelay(err, attempt)
+		if !retry || attempt > c.maxRetries {
+			return annos, err
+		}
+
+		l.Debug("request throttled by Dropbox, retrying",
+			zap.String("url", endpointURL),
+			zap.Int("attempt", attempt),
+			zap.Duration("delay", delay),
+			zap.Error(err),
+		)
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return annos, fmt.Errorf("%w (waiting to retry: %w)", err, sleepErr)
+		}
+	}
+}
+
+// requestHeader applies opts to an empty header.
+func requestHeader(opts []RequestOption) map[string]string {
+	header := map[string]string{}
+	for _, opt := range opts {
+		opt(header)
 	}
+	return header
+}
+
+// doRequestOnce executes a single attempt of doRequest with token. Dropbox
+// error responses are returned as *APIError.
+func (c *Client) doRequestOnce(
+	ctx context.Context,
+	token *oauth2.Token,
+	endpointURL string,
+	method string,
+	result any,
+	body any,
+	opts []RequestOption,
+) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
 
 	var reqOptions []uhttp.RequestOption
 	if body != nil {
@@ -96,6 +263,9 @@ func (c *Client) doRequest(
 	if body != nil {
 		headerOpts = append(headerOpts, uhttp.WithContentTypeJSONHeader())
 	}
+	for key, value := range requestHeader(opts) {
+		headerOpts = append(headerOpts, uhttp.WithHeader(key, value))
+	}
 	reqOptions = append(reqOptions, headerOpts...)
 
 	request, err := c.wrapper.NewRequest(ctx, method, parsedURL, reqOptions...)
@@ -105,24 +275,22 @@ func (c *Client) doRequest(
 
 	var doOptions []uhttp.DoOption
 	var ratelimitData v2.RateLimitDescription
-	var errResp DropboxError
 
 	if result != nil {
 		doOptions = append(doOptions, uhttp.WithJSONResponse(result))
 	}
 	doOptions = append(doOptions,
 		uhttp.WithRatelimitData(&ratelimitData),
-		uhttp.WithErrorResponse(&errResp),
 	)
 
 	response, err := c.wrapper.Do(request, doOptions...)
 	if err != nil {
+		err = wrapAPIError(response, err)
 		l.Debug("request failed",
 			zap.String("url", endpointURL),
 			zap.Error(err),
-			zap.String("dropbox_error", errResp.Message()),
 		)
-		return nil, err
+		return errorAnnotations(err), err
 	}
 	defer response.Body.Close()
 
@@ -131,3 +299,16 @@ func (c *Client) doRequest(
 
 	return annos, nil
 }
+
+// errorAnnotations returns the rate limit data of a failed request, so callers
+// report it the same way whether or not the request succeeded.
+func errorAnnotations(err error) annotations.Annotations {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.RateLimit == nil {
+		return nil
+	}
+
+	annos := annotations.Annotations{}
+	annos.WithRateLimiting(apiErr.RateLimit)
+	return annos
+}
@@ -0,0 +1,63 @@
+package dropbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func newTestClient(t *testing.T, server *httptest.Server) *Client {
+	t.Helper()
+
+	c, err := NewClient(context.Background(), Config{BaseURL: server.URL})
+	require.NoError(t, err)
+	c.TokenSource = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "test-token"})
+	return c
+}
+
+// TestDoRequest_SelectsMember verifies that AsMember selects the member and
+// that a request without arguments is sent without a body.
+func TestDoRequest_SelectsMember(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "dbmid:1", r.Header.Get("Dropbox-API-Select-User"))
+		require.Empty(t, r.Header.Get("Dropbox-API-Select-Admin"))
+		// RPC endpoints without arguments reject a JSON content type with an empty body.
+		require.Empty(t, r.Header.Get("Content-Type"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"used": 1024}`))
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, server)
+	var result struct {
+		Used uint64 `json:"used"`
+	}
+	_, err := c.doRequest(context.Background(), c.url("/2/users/get_space_usage"), http.MethodPost, &result, nil, AsMember("dbmid:1"))
+	require.NoError(t, err)
+	require.Equal(t, uint64(1024), result.Used)
+}
+
+func TestDoRequest_AdminAndPathRootOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "dbmid:admin", r.Header.Get("Dropbox-API-Select-Admin"))
+
+		var pathRoot map[string]string
+		require.NoError(t, json.Unmarshal([]byte(r.Header.Get("Dropbox-API-Path-Root")), &pathRoot))
+		require.Equal(t, map[string]string{".tag": "namespace_id", "namespace_id": "1234"}, pathRoot)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, server)
+	_, err := c.doRequest(context.Background(), c.url("/2/files/list_folder"), http.MethodPost, nil, map[string]string{"path": ""},
+		AsAdmin("dbmid:admin"), WithNamespace("1234"))
+	require.NoError(t, err)
+}
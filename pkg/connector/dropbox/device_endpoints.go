@@ -0,0 +1,30 @@
+package dropbox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// ListMembersDevices lists a page of team members' web sessions, desktop
+// clients and mobile clients. Pass the previous page's cursor, or "" for the
+// first page.
+// Based on API: POST /2/team/devices/list_members_devices.
+func (c *Client) ListMembersDevices(ctx context.Context, cursor string) (*ListMembersDevicesPayload, *v2.RateLimitDescription, error) {
+	body := ListMembersDevicesBody{
+		Cursor:                cursor,
+		IncludeWebSessions:    true,
+		IncludeDesktopClients: true,
+		IncludeMobileClients:  true,
+	}
+
+	result := &ListMembersDevicesPayload{}
+	annos, err := c.doRequest(ctx, c.url("/2/team/devices/list_members_devices"), http.MethodPost, result, body)
+	if err != nil {
+		return nil, getRateLimitFromAnnos(annos), fmt.Errorf("failed to list members' devices: %w", err)
+	}
+
+	return result, getRateLimitFromAnnos(annos), nil
+}
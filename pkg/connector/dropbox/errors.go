@@ -0,0 +1,241 @@
+package dropbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/ratelimit"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// APIError is an error response from the Dropbox API. Dropbox reports
+// endpoint errors as a nested union, e.g.
+//
+//	{"error_summary": "path/not_found/..", "error": {".tag": "path", "path": {".tag": "not_found"}}}
+//
+// which is decoded into Tags, outermost first. APIError implements GRPCStatus,
+// so status.Code(err) reports the code the tags map to.
+type APIError struct {
+	StatusCode int
+	Summary    string
+	// Tags is the path through the error union, outermost first.
+	Tags []string
+	// RequiredScope is set for missing_scope errors.
+	RequiredScope string
+	// RateLimit is set when the response carried rate limit headers.
+	RateLimit *v2.RateLimitDescription
+	// RetryAfter is how long Dropbox asked the client to wait before
+	// retrying, from the Retry-After header or the error's retry_after.
+	RetryAfter time.Duration
+	// Body is the complete response body.
+	Body []byte
+}
+
+// errorTagCodes maps error union tags to the gRPC code they represent. Tags are
+// matched innermost first, so the most specific tag wins.
+var errorTagCodes = map[string]codes.Code{
+	// Per the Dropbox API spec, *_not_found tags are reported by the member,
+	// group and secondary email endpoints for unknown IDs.
+	"id_not_found":          codes.NotFound,
+	"user_not_found":        codes.NotFound,
+	"group_not_found":       codes.NotFound,
+	"member_not_found":      codes.NotFound,
+	"not_found":             codes.NotFound,
+	"member_not_in_group":   codes.NotFound,
+	"user_not_in_team":      codes.NotFound,
+	"mapping_not_found":     codes.NotFound,
+	"team_member_not_found": codes.NotFound,
+
+	"duplicate_user":                   codes.AlreadyExists,
+	"user_already_on_team":             codes.AlreadyExists,
+	"duplicate_external_member_id":     codes.AlreadyExists,
+	"duplicate_member_persistent_id":   codes.AlreadyExists,
+	"group_name_already_used":          codes.AlreadyExists,
+	"external_id_already_in_use":       codes.AlreadyExists,
+	"persistent_id_used_by_other_user": codes.AlreadyExists,
+
+	"suspend_inactive_user":          codes.FailedPrecondition,
+	"unsuspend_non_suspended_member": codes.FailedPrecondition,
+	"suspend_last_admin":             codes.FailedPrecondition,
+	"remove_last_admin":              codes.FailedPrecondition,
+	"team_license_limit":             codes.ResourceExhausted,
+	"free_team_member_limit_reached": codes.ResourceExhausted,
+	"too_many_requests":              codes.ResourceExhausted,
+	"too_many_write_operations":      codes.ResourceExhausted,
+
+	"missing_scope":       codes.PermissionDenied,
+	"no_permission":       codes.PermissionDenied,
+	"access_denied":       codes.PermissionDenied,
+	"paper_access_denied": codes.PermissionDenied,
+
+	"invalid_access_token": codes.Unauthenticated,
+	"expired_access_token": codes.Unauthenticated,
+	"user_suspended":       codes.Unauthenticated,
+}
+
+func (e *APIError) Error() string {
+	message := e.Summary
+	if message == "" {
+		message = strings.Join(e.Tags, "/")
+	}
+	if message == "" {
+		message = http.StatusText(e.StatusCode)
+	}
+	return fmt.Sprintf("dropbox api error (status %d): %s", e.StatusCode, message)
+}
+
+// Tag returns the outermost error tag, or "" if the error had none.
+func (e *APIError) Tag() string {
+	if len(e.Tags) == 0 {
+		return ""
+	}
+	return e.Tags[0]
+}
+
+// HasTag reports whether tag appears anywhere in the error union.
+func (e *APIError) HasTag(tag string) bool {
+	return slices.Contains(e.Tags, tag)
+}
+
+// Code maps the error to a gRPC code, preferring the most specific known tag
+// and falling back to the HTTP status.
+func (e *APIError) Code() codes.Code {
+	for i := len(e.Tags) - 1; i >= 0; i-- {
+		if code, ok := errorTagCodes[e.Tags[i]]; ok {
+			return code
+		}
+	}
+
+	switch {
+	case e.StatusCode == http.StatusConflict:
+		// Dropbox uses 409 for every endpoint-specific error, so an unknown
+		// tag only says the request conflicted with the current state.
+		return codes.FailedPrecondition
+	case e.StatusCode == http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case e.StatusCode == http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case e.StatusCode == http.StatusForbidden:
+		return codes.PermissionDenied
+	case e.StatusCode == http.StatusBadRequest:
+		return codes.InvalidArgument
+	case e.StatusCode >= 500:
+		return codes.Unavailable
+	default:
+		return codes.Unknown
+	}
+}
+
+// GRPCStatus implements the interface status.FromError looks for.
+func (e *APIError) GRPCStatus() *status.Status {
+	st := status.New(e.Code(), e.Error())
+	if e.RateLimit != nil {
+		if withDetails, err := st.WithDetails(e.RateLimit); err == nil {
+			st = withDetails
+		}
+	}
+	return st
+}
+
+// HasErrorTag reports whether err is an *APIError whose union contains tag.
+func HasErrorTag(err error, tag string) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.HasTag(tag)
+}
+
+// newAPIError decodes a Dropbox error response. Bodies that aren't JSON, such
+// as the plain text Dropbox returns for malformed requests, become the summary.
+func newAPIError(statusCode int, header http.Header, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: statusCode, Body: body}
+	if rateLimit, err := ratelimit.ExtractRateLimitData(statusCode, &header); err == nil {
+		apiErr.RateLimit = rateLimit
+	}
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	var payload struct {
+		ErrorSummary     string          `json:"error_summary"`
+		Error            json.RawMessage `json:"error"`
+		ErrorDescription string          `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		apiErr.Summary = strings.TrimSpace(string(body))
+		return apiErr
+	}
+	apiErr.Summary = payload.ErrorSummary
+
+	// The OAuth token endpoint reports errors as {"error": "invalid_grant",
+	// "error_description": "..."} instead of a union.
+	var oauthError string
+	if err := json.Unmarshal(payload.Error, &oauthError); err == nil && oauthError != "" {
+		apiErr.Tags = []string{oauthError}
+		if apiErr.Summary == "" {
+			apiErr.Summary = strings.TrimSpace(oauthError + ": " + payload.ErrorDescription)
+		}
+		return apiErr
+	}
+
+	// Walk the union: each level names its variant in ".tag" and, when the
+	// variant carries a value, nests it under a key of the same name. Rate
+	// limit errors instead put the union under "reason", next to retry_after.
+	raw := payload.Error
+	for len(raw) > 0 {
+		var level map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &level); err != nil {
+			break
+		}
+		if scope, ok := level["required_scope"]; ok {
+			_ = json.Unmarshal(scope, &apiErr.RequiredScope)
+		}
+		var retryAfter int
+		if err := json.Unmarshal(level["retry_after"], &retryAfter); err == nil && apiErr.RetryAfter == 0 {
+			apiErr.RetryAfter = time.Duration(retryAfter) * time.Second
+		}
+
+		var tag string
+		if err := json.Unmarshal(level[".tag"], &tag); err != nil || tag == "" {
+			raw = level["reason"]
+			continue
+		}
+		apiErr.Tags = append(apiErr.Tags, tag)
+		raw = level[tag]
+	}
+
+	// Some errors (e.g. from the auth layer) only carry the summary, which
+	// spells out the same path: "user_not_found/...".
+	if len(apiErr.Tags) == 0 && apiErr.Summary != "" {
+		for _, tag := range strings.Split(apiErr.Summary, "/") {
+			tag = strings.TrimSpace(tag)
+			if tag == "" || strings.HasPrefix(tag, ".") {
+				break
+			}
+			apiErr.Tags = append(apiErr.Tags, tag)
+		}
+	}
+
+	return apiErr
+}
+
+// wrapAPIError replaces err with an *APIError decoded from res when res is an
+// error response. uhttp leaves the buffered body readable on res.
+func wrapAPIError(res *http.Response, err error) error {
+	if res == nil || res.StatusCode < 300 || res.Body == nil {
+		return err
+	}
+
+	body, readErr := io.ReadAll(res.Body)
+	if readErr != nil {
+		return err
+	}
+	return newAPIError(res.StatusCode, res.Header, body)
+}
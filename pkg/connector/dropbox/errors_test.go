@@ -0,0 +1,112 @@
+package dropbox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNewAPIError_DecodesNestedUnion(t *testing.T) {
+	apiErr := newAPIError(http.StatusConflict, http.Header{}, []byte(
+		`{"error_summary": "path/not_found/..", "error": {".tag": "path", "path": {".tag": "not_found"}}}`))
+
+	require.Equal(t, []string{"path", "not_found"}, apiErr.Tags)
+	require.Equal(t, "path", apiErr.Tag())
+	require.True(t, apiErr.HasTag("not_found"))
+	require.Equal(t, codes.NotFound, status.Code(apiErr))
+}
+
+func TestNewAPIError_Codes(t *testing.T) {
+	cases := []struct {
+		name       string
+		statusCode int
+		body       string
+		want       codes.Code
+	}{
+		{"id not found", http.StatusConflict, `{"error": {".tag": "id_not_found"}}`, codes.NotFound},
+		{"duplicate group member", http.StatusConflict, `{"error": {".tag": "duplicate_user"}}`, codes.AlreadyExists},
+		{"suspend inactive user", http.StatusConflict, `{"error": {".tag": "suspend_inactive_user"}}`, codes.FailedPrecondition},
+		{"license limit", http.StatusConflict, `{"error": {".tag": "team_license_limit"}}`, codes.ResourceExhausted},
+		{"missing scope", http.StatusUnauthorized, `{"error": {".tag": "missing_scope", "required_scope": "groups.read"}}`, codes.PermissionDenied},
+		{"expired token", http.StatusUnauthorized, `{"error": {".tag": "expired_access_token"}}`, codes.Unauthenticated},
+		{"rate limited", http.StatusTooManyRequests, `{"error": {".tag": "too_many_requests"}}`, codes.ResourceExhausted},
+		{"unknown endpoint error", http.StatusConflict, `{"error": {".tag": "other"}}`, codes.FailedPrecondition},
+		{"summary only", http.StatusConflict, `{"error_summary": "user_not_found/.."}`, codes.NotFound},
+		{"plain text bad request", http.StatusBadRequest, `Error in call to API function "team/members/list_v2": bad limit`, codes.InvalidArgument},
+		{"server error", http.StatusInternalServerError, ``, codes.Unavailable},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			apiErr := newAPIError(tc.statusCode, http.Header{}, []byte(tc.body))
+			require.Equal(t, tc.want, status.Code(fmt.Errorf("wrapped: %w", apiErr)))
+		})
+	}
+
+	apiErr := newAPIError(http.StatusUnauthorized, http.Header{}, []byte(`{"error": {".tag": "missing_scope", "required_scope": "groups.read"}}`))
+	require.Equal(t, "groups.read", apiErr.RequiredScope)
+}
+
+// TestAddUserToGroup_ReturnsTypedError verifies that endpoint errors surface as
+// *APIError with the mapped code rather than uhttp's generic 409 mapping.
+func TestAddUserToGroup_ReturnsTypedError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(`{"error_summary": "group_not_found/..", "error": {".tag": "group_not_found"}}`))
+	}))
+	defer server.Close()
+
+	_, err := newTestClient(t, server).AddUserToGroup(context.Background(), "g:1", "dbmid:1", "member")
+	require.Equal(t, codes.NotFound, status.Code(err))
+	require.True(t, HasErrorTag(err, "group_not_found"))
+}
+
+// TestListUsers_ReturnsRateLimitWithError verifies that a failed list call
+// returns a typed error carrying the full body alongside the rate limit data,
+// and no payload.
+func TestListUsers_ReturnsRateLimitWithError(t *testing.T) {
+	body := `{"error_summary": "too_many_requests/..", "error": {"reason": {".tag": "too_many_requests"}, "retry_after": 30}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/2/team/members/list_v2", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, server)
+	c.maxRetries = 0
+
+	payload, rateLimit, err := c.ListUsers(context.Background(), 0)
+	require.Nil(t, payload)
+	require.NotNil(t, rateLimit)
+	require.NotNil(t, rateLimit.GetResetAt())
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	require.JSONEq(t, body, string(apiErr.Body))
+	require.Equal(t, 30*time.Second, apiErr.RetryAfter)
+}
+
+func TestRequestAccessToken_ReturnsOAuthError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error": "invalid_grant", "error_description": "code doesn't exist or has expired"}`))
+	}))
+	defer server.Close()
+
+	_, _, _, err := newTestClient(t, server).RequestAccessToken(context.Background(), &AuthorizationCode{Code: "stale"})
+	require.True(t, HasErrorTag(err, "invalid_grant"))
+	require.ErrorContains(t, err, "code doesn't exist or has expired")
+}
@@ -9,14 +9,12 @@ import (
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 )
 
-const eventsListLimitDefault = 1000
-
 // GetTeamEvents fetches a page of the team event audit log, optionally filtered
 // by event category (e.g. "logins") and a start time.
 // Based on API: POST /2/team_log/get_events.
 func (c *Client) GetTeamEvents(ctx context.Context, category string, startTime *time.Time, limit int) (*GetTeamEventsPayload, *v2.RateLimitDescription, error) {
 	if limit == 0 {
-		limit = eventsListLimitDefault
+		limit = c.pageSize(eventsListing)
 	}
 
 	body := GetTeamEventsBody{Limit: limit}
@@ -30,7 +28,32 @@ func (c *Client) GetTeamEvents(ctx context.Context, category string, startTime *
 	result := &GetTeamEventsPayload{}
 	annos, err := c.doRequest(ctx, c.url("/2/team_log/get_events"), http.MethodPost, result, body)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get team events: %w", err)
+		return nil, getRateLimitFromAnnos(annos), fmt.Errorf("failed to get team events: %w", err)
+	}
+
+	return result, getRateLimitFromAnnos(annos), nil
+}
+
+// GetAccountEvents fetches a page of the team event audit log's events of one
+// type involving an account, from startTime on. Dropbox rejects a category
+// together with an event type, so it filters by type only.
+// Based on API: POST /2/team_log/get_events.
+func (c *Client) GetAccountEvents(ctx context.Context, accountID string, eventType string, startTime time.Time, limit int) (*GetTeamEventsPayload, *v2.RateLimitDescription, error) {
+	if limit == 0 {
+		limit = c.pageSize(eventsListing)
+	}
+
+	body := GetTeamEventsBody{
+		Limit:     limit,
+		AccountID: accountID,
+		EventType: &Tag{Tag: eventType},
+		Time:      &TimeRange{StartTime: startTime.UTC().Format(TimestampFormat)},
+	}
+
+	result := &GetTeamEventsPayload{}
+	annos, err := c.doRequest(ctx, c.url("/2/team_log/get_events"), http.MethodPost, result, body)
+	if err != nil {
+		return nil, getRateLimitFromAnnos(annos), fmt.Errorf("failed to get account events: %w", err)
 	}
 
 	return result, getRateLimitFromAnnos(annos), nil
@@ -42,7 +65,7 @@ func (c *Client) GetTeamEventsContinue(ctx context.Context, cursor string) (*Get
 	result := &GetTeamEventsPayload{}
 	annos, err := c.doRequest(ctx, c.url("/2/team_log/get_events/continue"), http.MethodPost, result, GetTeamEventsContinueBody{Cursor: cursor})
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to continue team events: %w", err)
+		return nil, getRateLimitFromAnnos(annos), fmt.Errorf("failed to continue team events: %w", err)
 	}
 
 	return result, getRateLimitFromAnnos(annos), nil
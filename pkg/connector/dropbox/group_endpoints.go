@@ -1,14 +1,11 @@
 package dropbox
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
-	"github.com/conductorone/baton-sdk/pkg/uhttp"
 )
 
 const groupDefaultLimit = 100
@@ -21,89 +18,33 @@ func DefaultListGroupsBody() ListGroupsBody {
 
 // docs: https://www.dropbox.com/developers/documentation/http/teams#team-groups-list
 func (c *Client) ListGroups(ctx context.Context, limit int) (*ListGroupsPayload, *v2.RateLimitDescription, error) {
-	token, err := c.TokenSource.Token()
-	if err != nil {
-		return nil, nil, err
-	}
-
 	body := DefaultListGroupsBody()
+	body.Limit = c.pageSize(groupsListing)
 	if limit != 0 {
 		body.Limit = limit
 	}
 
-	reader := new(bytes.Buffer)
-	err = json.NewEncoder(reader).Encode(body)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url("/2/team/groups/list"), reader)
-	if err != nil {
-		return nil, nil, err
-	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token.AccessToken))
-	req.Header.Set("Content-Type", "application/json")
-
 	var target ListGroupsPayload
-	var ratelimitData v2.RateLimitDescription
-	res, err := c.wrapper.Do(req,
-		uhttp.WithJSONResponse(&target),
-		uhttp.WithRatelimitData(&ratelimitData),
-	)
+	annos, err := c.doRequest(ctx, c.url("/2/team/groups/list"), http.MethodPost, &target, body)
 	if err != nil {
-		logBody(ctx, res)
-		return nil, &ratelimitData, err
+		return nil, getRateLimitFromAnnos(annos), fmt.Errorf("failed to list groups: %w", err)
 	}
 
-	defer res.Body.Close()
-	if res.StatusCode != http.StatusOK {
-		logBody(ctx, res)
-		return nil, &ratelimitData, err
-	}
-
-	return &target, &ratelimitData, nil
+	return &target, getRateLimitFromAnnos(annos), nil
 }
 
 func (c *Client) ListGroupsContinue(ctx context.Context, cursor string) (*ListGroupsPayload, *v2.RateLimitDescription, error) {
-	token, err := c.TokenSource.Token()
-	if err != nil {
-		return nil, nil, err
-	}
-
 	body := struct {
 		Cursor string `json:"cursor"`
 	}{Cursor: cursor}
 
-	reader := new(bytes.Buffer)
-	err = json.NewEncoder(reader).Encode(body)
-	if err != nil {
-		return nil, nil, err
-	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url("/2/team/groups/list/continue"), reader)
-	if err != nil {
-		return nil, nil, err
-	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token.AccessToken))
-	req.Header.Set("Content-Type", "application/json")
-
 	var target ListGroupsPayload
-	var ratelimitData v2.RateLimitDescription
-	res, err := c.wrapper.Do(req,
-		uhttp.WithJSONResponse(&target),
-		uhttp.WithRatelimitData(&ratelimitData),
-	)
+	annos, err := c.doRequest(ctx, c.url("/2/team/groups/list/continue"), http.MethodPost, &target, body)
 	if err != nil {
-		logBody(ctx, res)
-		return nil, &ratelimitData, err
-	}
-
-	defer res.Body.Close()
-	if res.StatusCode != http.StatusOK {
-		logBody(ctx, res)
-		return nil, &ratelimitData, err
+		return nil, getRateLimitFromAnnos(annos), fmt.Errorf("failed to continue listing groups: %w", err)
 	}
 
-	return &target, &ratelimitData, nil
+	return &target, getRateLimitFromAnnos(annos), nil
 }
 
 func DefaultGroupMembersBody() ListGroupMembersBody {
@@ -116,103 +57,41 @@ func DefaultGroupMembersBody() ListGroupMembersBody {
 }
 
 func (c *Client) ListGroupMembers(ctx context.Context, groupId string, limit int) (*ListGroupMembersPayload, *v2.RateLimitDescription, error) {
-	token, err := c.TokenSource.Token()
-	if err != nil {
-		return nil, nil, err
-	}
-
 	body := DefaultGroupMembersBody()
 	if groupId == "" {
 		return nil, nil, fmt.Errorf("groupId is required")
 	}
 	body.Group.GroupID = groupId
 
+	body.Limit = c.pageSize(groupMembersListing)
 	if limit != 0 {
 		body.Limit = limit
 	}
 
-	reader := new(bytes.Buffer)
-	err = json.NewEncoder(reader).Encode(body)
-	if err != nil {
-		return nil, nil, err
-	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url("/2/team/groups/members/list"), reader)
-	if err != nil {
-		return nil, nil, err
-	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token.AccessToken))
-	req.Header.Set("Content-Type", "application/json")
-
 	var target ListGroupMembersPayload
-	var ratelimitData v2.RateLimitDescription
-	res, err := c.wrapper.Do(req,
-		uhttp.WithJSONResponse(&target),
-		uhttp.WithRatelimitData(&ratelimitData),
-	)
-
+	annos, err := c.doRequest(ctx, c.url("/2/team/groups/members/list"), http.MethodPost, &target, body)
 	if err != nil {
-		logBody(ctx, res)
-		return nil, &ratelimitData, err
-	}
-
-	defer res.Body.Close()
-	if res.StatusCode != http.StatusOK {
-		logBody(ctx, res)
-		return nil, &ratelimitData, err
+		return nil, getRateLimitFromAnnos(annos), fmt.Errorf("failed to list group members: %w", err)
 	}
 
-	return &target, &ratelimitData, nil
+	return &target, getRateLimitFromAnnos(annos), nil
 }
 
 func (c *Client) ListGroupMembersContinue(ctx context.Context, cursor string) (*ListGroupMembersPayload, *v2.RateLimitDescription, error) {
-	token, err := c.TokenSource.Token()
-	if err != nil {
-		return nil, nil, err
-	}
-
 	body := struct {
 		Cursor string `json:"cursor"`
 	}{Cursor: cursor}
 
-	reader := new(bytes.Buffer)
-	err = json.NewEncoder(reader).Encode(body)
-	if err != nil {
-		return nil, nil, err
-	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url("/2/team/groups/members/list/continue"), reader)
-	if err != nil {
-		return nil, nil, err
-	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token.AccessToken))
-	req.Header.Set("Content-Type", "application/json")
-
 	var target ListGroupMembersPayload
-	var ratelimitData v2.RateLimitDescription
-	res, err := c.wrapper.Do(req,
-		uhttp.WithJSONResponse(&target),
-		uhttp.WithRatelimitData(&ratelimitData),
-	)
-
+	annos, err := c.doRequest(ctx, c.url("/2/team/groups/members/list/continue"), http.MethodPost, &target, body)
 	if err != nil {
-		logBody(ctx, res)
-		return nil, &ratelimitData, err
+		return nil, getRateLimitFromAnnos(annos), fmt.Errorf("failed to continue listing group members: %w", err)
 	}
 
-	defer res.Body.Close()
-	if res.StatusCode != http.StatusOK {
-		logBody(ctx, res)
-		return nil, &ratelimitData, err
-	}
-
-	return &target, &ratelimitData, nil
+	return &target, getRateLimitFromAnnos(annos), nil
 }
 
 func (c *Client) RemoveUserFromGroup(ctx context.Context, groupId string, teamMemberID string) (*v2.RateLimitDescription, error) {
-	token, err := c.TokenSource.Token()
-	if err != nil {
-		return nil, err
-	}
-
 	body := RemoveUserFromGroupBody{
 		Group: GroupIdTag{
 			GroupID: groupId,
@@ -226,43 +105,15 @@ func (c *Client) RemoveUserFromGroup(ctx context.Context, groupId string, teamMe
 		},
 	}
 
-	buffer := new(bytes.Buffer)
-	err = json.NewEncoder(buffer).Encode(body)
-	if err != nil {
-		return nil, err
-	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url("/2/team/groups/members/remove"), buffer)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token.AccessToken))
-	req.Header.Set("Content-Type", "application/json")
-
-	var ratelimitData v2.RateLimitDescription
-	res, err := c.wrapper.Do(req,
-		uhttp.WithRatelimitData(&ratelimitData),
-	)
-
+	annos, err := c.doRequest(ctx, c.url("/2/team/groups/members/remove"), http.MethodPost, nil, body)
 	if err != nil {
-		logBody(ctx, res)
-		return &ratelimitData, err
-	}
-
-	defer res.Body.Close()
-	if res.StatusCode != http.StatusOK {
-		logBody(ctx, res)
-		return &ratelimitData, err
+		return getRateLimitFromAnnos(annos), fmt.Errorf("failed to remove user from group: %w", err)
 	}
 
-	return &ratelimitData, nil
+	return getRateLimitFromAnnos(annos), nil
 }
 
 func (c *Client) AddUserToGroup(ctx context.Context, groupId, teamMemberID, accessType string) (*v2.RateLimitDescription, error) {
-	token, err := c.TokenSource.Token()
-	if err != nil {
-		return nil, err
-	}
-
 	body := AddUserToGroupBody{
 		Group: GroupIdTag{
 			Tag:     "group_id",
@@ -279,33 +130,10 @@ func (c *Client) AddUserToGroup(ctx context.Context, groupId, teamMemberID, acce
 		},
 	}
 
-	buf := new(bytes.Buffer)
-	err = json.NewEncoder(buf).Encode(body)
+	annos, err := c.doRequest(ctx, c.url("/2/team/groups/members/add"), http.MethodPost, nil, body)
 	if err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url("/2/team/groups/members/add"), buf)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token.AccessToken))
-	req.Header.Set("Content-Type", "application/json")
-
-	var ratelimitData v2.RateLimitDescription
-	res, err := c.wrapper.Do(req,
-		uhttp.WithRatelimitData(&ratelimitData),
-	)
-	if err != nil {
-		logBody(ctx, res)
-		return &ratelimitData, err
-	}
-
-	defer res.Body.Close()
-	if res.StatusCode != http.StatusOK {
-		logBody(ctx, res)
-		return &ratelimitData, err
+		return getRateLimitFromAnnos(annos), fmt.Errorf("failed to add user to group: %w", err)
 	}
 
-	return &ratelimitData, nil
+	return getRateLimitFromAnnos(annos), nil
 }
@@ -0,0 +1,34 @@
+package dropbox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// ListNamespaces lists the first page of the team's namespaces: team folders,
+// shared folders, members' home folders and app folders.
+// Based on API: POST /2/team/namespaces/list.
+func (c *Client) ListNamespaces(ctx context.Context) (*ListNamespacesPayload, *v2.RateLimitDescription, error) {
+	result := &ListNamespacesPayload{}
+	annos, err := c.doRequest(ctx, c.url("/2/team/namespaces/list"), http.MethodPost, result, ListNamespacesBody{Limit: MaxPageSize})
+	if err != nil {
+		return nil, getRateLimitFromAnnos(annos), fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	return result, getRateLimitFromAnnos(annos), nil
+}
+
+// ListNamespacesContinue lists the page of namespaces after cursor.
+// Based on API: POST /2/team/namespaces/list/continue.
+func (c *Client) ListNamespacesContinue(ctx context.Context, cursor string) (*ListNamespacesPayload, *v2.RateLimitDescription, error) {
+	result := &ListNamespacesPayload{}
+	annos, err := c.doRequest(ctx, c.url("/2/team/namespaces/list/continue"), http.MethodPost, result, ListNamespacesContinueBody{Cursor: cursor})
+	if err != nil {
+		return nil, getRateLimitFromAnnos(annos), fmt.Errorf("failed to continue listing namespaces: %w", err)
+	}
+
+	return result, getRateLimitFromAnnos(annos), nil
+}
@@ -0,0 +1,142 @@
+package dropbox
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxPageSize is the largest limit Dropbox accepts on the member, group
+	// and team_log listing endpoints.
+	MaxPageSize = 1000
+	// DefaultPageSize is the member and group page size when none is set.
+	DefaultPageSize = 100
+
+	// minAdaptivePageSize is the smallest page adaptive sizing shrinks to.
+	minAdaptivePageSize = 25
+	// fastPageResponse is the response time under which adaptive sizing grows
+	// the page size.
+	fastPageResponse = 2 * time.Second
+)
+
+// listing identifies a paginated listing whose page size is tracked
+// separately.
+type listing string
+
+const (
+	membersListing      listing = "members"
+	groupsListing       listing = "groups"
+	groupMembersListing listing = "group_members"
+	eventsListing       listing = "events"
+)
+
+// listingOf returns the listing an endpoint path belongs to, or "" for
+// endpoints that aren't paginated listings.
+func listingOf(path string) listing {
+	switch {
+	case strings.HasPrefix(path, "/2/team/members/list"):
+		return membersListing
+	case strings.HasPrefix(path, "/2/team/groups/members/list"):
+		return groupMembersListing
+	case strings.HasPrefix(path, "/2/team/groups/list"):
+		return groupsListing
+	case strings.HasPrefix(path, "/2/team_log/get_events"):
+		return eventsListing
+	default:
+		return ""
+	}
+}
+
+// pageSizer picks the limit for new listings. With adaptive sizing, the limit
+// doubles after each fast response and halves after a timeout or rate limit.
+// Dropbox fixes the page size when a listing starts and its /continue
+// endpoints take only a cursor, so a change applies to the next listing
+// started, e.g. the next group's members. A listing already under way, such
+// as the members of a large team, keeps the size it started with.
+type pageSizer struct {
+	mu       sync.Mutex
+	size     int
+	adaptive bool
+}
+
+func newPageSizer(size int, adaptive bool) *pageSizer {
+	return &pageSizer{size: min(max(size, 1), MaxPageSize), adaptive: adaptive}
+}
+
+func (p *pageSizer) get() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.size
+}
+
+// observe adjusts the page size after a request that took elapsed and failed
+// with err, if it failed.
+func (p *pageSizer) observe(elapsed time.Duration, err error) {
+	if !p.adaptive {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case isTimeout(err) || isRateLimited(err):
+		p.size = max(p.size/2, minAdaptivePageSize)
+	case err == nil && elapsed < fastPageResponse:
+		p.size = min(p.size*2, MaxPageSize)
+	}
+}
+
+// isTimeout reports whether err is a request or gateway timeout.
+func isTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusGatewayTimeout
+}
+
+// isRateLimited reports whether err is Dropbox throttling the request.
+func isRateLimited(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusTooManyRequests ||
+		apiErr.HasTag("too_many_requests") ||
+		apiErr.HasTag("too_many_write_operations")
+}
+
+// SetPageSize sets the member and group page size, DefaultPageSize if zero,
+// and whether page sizes adapt to response times.
+func (c *Client) SetPageSize(size int, adaptive bool) {
+	if size == 0 {
+		size = DefaultPageSize
+	}
+	c.pageSizers = newPageSizers(size, adaptive)
+}
+
+func newPageSizers(size int, adaptive bool) map[listing]*pageSizer {
+	return map[listing]*pageSizer{
+		membersListing:      newPageSizer(size, adaptive),
+		groupsListing:       newPageSizer(size, adaptive),
+		groupMembersListing: newPageSizer(size, adaptive),
+		eventsListing:       newPageSizer(MaxPageSize, adaptive),
+	}
+}
+
+// pageSize returns the limit for a new listing.
+func (c *Client) pageSize(l listing) int {
+	return c.pageSizers[l].get()
+}
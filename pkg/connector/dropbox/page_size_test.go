@@ -0,0 +1,66 @@
+package dropbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageSizer_Adaptive(t *testing.T) {
+	sizer := newPageSizer(400, true)
+
+	sizer.observe(100*time.Millisecond, nil)
+	require.Equal(t, 800, sizer.get())
+	sizer.observe(100*time.Millisecond, nil)
+	require.Equal(t, MaxPageSize, sizer.get())
+
+	// Slow responses hold the size.
+	sizer.observe(5*time.Second, nil)
+	require.Equal(t, MaxPageSize, sizer.get())
+
+	sizer.observe(time.Second, &APIError{StatusCode: http.StatusTooManyRequests})
+	require.Equal(t, 500, sizer.get())
+	sizer.observe(time.Second, context.DeadlineExceeded)
+	require.Equal(t, 250, sizer.get())
+
+	for range 10 {
+		sizer.observe(time.Second, &APIError{StatusCode: http.StatusGatewayTimeout})
+	}
+	require.Equal(t, minAdaptivePageSize, sizer.get())
+}
+
+func TestPageSizer_FixedIgnoresResponses(t *testing.T) {
+	sizer := newPageSizer(300, false)
+	sizer.observe(time.Millisecond, nil)
+	sizer.observe(time.Second, context.DeadlineExceeded)
+	require.Equal(t, 300, sizer.get())
+}
+
+// TestListGroupMembers_UsesAdaptivePageSize verifies that each new listing
+// starts with the page size the previous responses led to.
+func TestListGroupMembers_UsesAdaptivePageSize(t *testing.T) {
+	var limits []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body ListGroupMembersBody
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		limits = append(limits, body.Limit)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"members": [], "has_more": false}`))
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, server)
+	c.SetPageSize(250, true)
+
+	for range 3 {
+		_, _, err := c.ListGroupMembers(context.Background(), "g:1", 0)
+		require.NoError(t, err)
+	}
+	require.Equal(t, []int{250, 500, 1000}, limits)
+}
@@ -1,5 +1,7 @@
 package dropbox
 
+import "encoding/json"
+
 // Common Types
 
 // Tag represents a Dropbox API discriminator field used to indicate object types.
@@ -7,26 +9,6 @@ type Tag struct {
 	Tag string `json:".tag"`
 }
 
-// DropboxError represents the standard error response from Dropbox API.
-// Implements the uhttp.ErrorResponse interface.
-type DropboxError struct {
-	ErrorSummary string `json:"error_summary"`
-	Error        struct {
-		Tag string `json:".tag"`
-	} `json:"error"`
-}
-
-// Message implements the ErrorResponse interface required by uhttp.WithErrorResponse.
-func (e *DropboxError) Message() string {
-	if e.ErrorSummary != "" {
-		return e.ErrorSummary
-	}
-	if e.Error.Tag != "" {
-		return e.Error.Tag
-	}
-	return "unknown dropbox error"
-}
-
 // EmailTag represents an email-based identifier used in Dropbox API requests.
 type EmailTag struct {
 	Tag   string `json:".tag"`
@@ -75,13 +57,58 @@ type UserPayload struct {
 
 // Profile represents a user's profile information in Dropbox.
 type Profile struct {
-	AccountID      string   `json:"account_id"`
-	TeamMemberID   string   `json:"team_member_id"`
-	Name           Name     `json:"name"`
-	Email          string   `json:"email"`
-	Groups         []string `json:"groups"`
-	Status         Tag      `json:"status"`
-	MembershipType Tag      `json:"membership_type"`
+	AccountID       string           `json:"account_id"`
+	TeamMemberID    string           `json:"team_member_id"`
+	Name            Name             `json:"name"`
+	Email           string           `json:"email"`
+	SecondaryEmails []SecondaryEmail `json:"secondary_emails"`
+	Groups          []string         `json:"groups"`
+	Status          Tag              `json:"status"`
+	MembershipType  Tag              `json:"membership_type"`
+	// InvitedOn is set only for members whose invitation is still pending
+	// (status "invited"), in TimestampFormat.
+	InvitedOn string `json:"invited_on,omitempty"`
+	// JoinedOn and SuspendedOn are in TimestampFormat and only set once the
+	// member has joined or been suspended, respectively.
+	JoinedOn    string `json:"joined_on,omitempty"`
+	SuspendedOn string `json:"suspended_on,omitempty"`
+	// ExternalID is the identifier an admin (or a directory sync) assigned to
+	// the member; PersistentID is the SAML persistent ID used for SSO.
+	ExternalID            string `json:"external_id,omitempty"`
+	PersistentID          string `json:"persistent_id,omitempty"`
+	AccountType           Tag    `json:"account_type"`
+	IsDirectoryRestricted bool   `json:"is_directory_restricted"`
+	// ProfilePhotoURL is only set for members who have uploaded a photo.
+	ProfilePhotoURL string `json:"profile_photo_url,omitempty"`
+}
+
+// SecondaryEmail represents an alias address attached to a team member's
+// account in addition to their primary email.
+type SecondaryEmail struct {
+	Email      string `json:"email"`
+	IsVerified bool   `json:"is_verified"`
+}
+
+// GetMemberInfoBody represents the request body for team/members/get_info_v2.
+type GetMemberInfoBody struct {
+	Members []TeamMemberIdTag `json:"members"`
+}
+
+// GetMemberInfoPayload represents the response from team/members/get_info_v2.
+type GetMemberInfoPayload struct {
+	MembersInfo []MemberInfoItem `json:"members_info"`
+}
+
+// MemberInfoItem is one entry of a get_info_v2 response. Tag is "member_info"
+// when the member was found; the variant is a struct, so Dropbox sends its
+// fields, the same shape returned by team/members/list_v2, next to the tag:
+//
+//	{".tag": "member_info", "profile": {...}, "roles": [...]}
+//
+// Any other tag (e.g. "id_not_found") means the member doesn't exist.
+type MemberInfoItem struct {
+	Tag string `json:".tag"`
+	UserPayload
 }
 
 // Account Provisioning
@@ -120,6 +147,82 @@ type RemoveMemberResponse struct {
 	Tag string `json:".tag"`
 }
 
+// Invitations
+
+// SendWelcomeEmailBody represents the request body for team/members/send_welcome_email.
+// The endpoint takes a bare UserSelectorArg rather than wrapping it in a "user" field.
+type SendWelcomeEmailBody = TeamMemberIdTag
+
+// Secondary Emails
+
+// UserSecondaryEmailsArg pairs a team member with the secondary emails an
+// add, delete or resend request applies to.
+type UserSecondaryEmailsArg struct {
+	User            TeamMemberIdTag `json:"user"`
+	SecondaryEmails []string        `json:"secondary_emails"`
+}
+
+// AddSecondaryEmailsBody represents the request body for team/members/secondary_emails/add.
+type AddSecondaryEmailsBody struct {
+	NewSecondaryEmails []UserSecondaryEmailsArg `json:"new_secondary_emails"`
+}
+
+// DeleteSecondaryEmailsBody represents the request body for team/members/secondary_emails/delete.
+type DeleteSecondaryEmailsBody struct {
+	EmailsToDelete []UserSecondaryEmailsArg `json:"emails_to_delete"`
+}
+
+// ResendVerificationEmailsBody represents the request body for
+// team/members/secondary_emails/resend_verification_emails.
+type ResendVerificationEmailsBody struct {
+	EmailsToResend []UserSecondaryEmailsArg `json:"emails_to_resend"`
+}
+
+// SecondaryEmailsPayload represents the response from the secondary_emails
+// add, delete and resend_verification_emails endpoints. Each entry in Results
+// corresponds to one user in the request.
+type SecondaryEmailsPayload struct {
+	Results []UserSecondaryEmailsResult `json:"results"`
+}
+
+// UserSecondaryEmailsResult is the per-user outcome of a secondary_emails
+// request. Tag is "success" when the user was resolved, in which case Results
+// holds one entry per requested email; any other tag (e.g. "invalid_user")
+// means none of the emails were processed.
+type UserSecondaryEmailsResult struct {
+	Tag     string                 `json:".tag"`
+	Results []SecondaryEmailResult `json:"results"`
+}
+
+// SecondaryEmailResult is the per-email outcome of a secondary_emails request.
+// Dropbox encodes it as a union whose value is the email address, stored
+// under a key matching the tag (e.g. {".tag": "not_found", "not_found": "a@b.com"}).
+type SecondaryEmailResult struct {
+	Tag   string
+	Email string
+}
+
+// UnmarshalJSON decodes the union by reading the email from the field named after the tag.
+func (r *SecondaryEmailResult) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var tag string
+	if err := json.Unmarshal(raw[".tag"], &tag); err != nil {
+		return err
+	}
+	r.Tag = tag
+
+	if value, ok := raw[tag]; ok {
+		// Some variants (e.g. unknown_error) carry a struct rather than a bare
+		// email; only string values are meaningful here.
+		_ = json.Unmarshal(value, &r.Email)
+	}
+	return nil
+}
+
 // Roles
 
 // Role represents a team role in Dropbox.
@@ -129,6 +232,12 @@ type Role struct {
 	RoleID      string `json:"role_id"`
 }
 
+// AvailableRolesPayload represents the response from the
+// get_available_team_member_roles API endpoint.
+type AvailableRolesPayload struct {
+	Roles []Role `json:"roles"`
+}
+
 // addRoleToUserBody represents the request body for role operations.
 type addRoleToUserBody struct {
 	NewRoles   []string        `json:"new_roles"`
@@ -221,9 +330,11 @@ type TimeRange struct {
 
 // GetTeamEventsBody represents the request body for team_log/get_events.
 type GetTeamEventsBody struct {
-	Limit    int               `json:"limit,omitempty"`
-	Category *EventCategoryTag `json:"category,omitempty"`
-	Time     *TimeRange        `json:"time,omitempty"`
+	Limit     int               `json:"limit,omitempty"`
+	AccountID string            `json:"account_id,omitempty"`
+	Category  *EventCategoryTag `json:"category,omitempty"`
+	EventType *Tag              `json:"event_type,omitempty"`
+	Time      *TimeRange        `json:"time,omitempty"`
 }
 
 // GetTeamEventsContinueBody represents the request body for team_log/get_events/continue.
@@ -239,47 +350,126 @@ type GetTeamEventsPayload struct {
 	HasMore bool        `json:"has_more"`
 }
 
-// TeamEvent represents a single entry in the Dropbox team event audit log.
-// Only the fields needed to derive last-login usage events are modeled; the
-// full Dropbox EventDetails union is not parsed.
-type TeamEvent struct {
-	Timestamp     string       `json:"timestamp"`
-	EventCategory Tag          `json:"event_category"`
-	EventType     Tag          `json:"event_type"`
-	Actor         ActorLogInfo `json:"actor"`
-}
-
-// ActorLogInfo represents the entity who performed a team log event. Only the
-// "user" and "admin" variants are parsed (both wrap a UserLogInfo — Dropbox
-// logs a team member's own actions under "admin" instead of "user" when that
-// member has admin permissions); other actor kinds (app, dropbox, anonymous,
-// reseller) are ignored by callers.
-type ActorLogInfo struct {
-	Tag   string       `json:".tag"`
-	User  *UserLogInfo `json:"user,omitempty"`
-	Admin *UserLogInfo `json:"admin,omitempty"`
-}
-
-// UserLogInfo returns the team member behind this event, whether they acted
-// as a plain "user" or as an "admin" (Dropbox logs a team member's own
-// actions under "admin" instead of "user" when that member has admin
-// permissions). Returns nil for any other actor kind (app, dropbox,
-// anonymous, reseller).
-func (a ActorLogInfo) UserInfo() *UserLogInfo {
-	switch a.Tag {
-	case "user":
-		return a.User
-	case "admin":
-		return a.Admin
-	default:
+// TeamInfoPayload is the response of team/get_info.
+type TeamInfoPayload struct {
+	Name                string `json:"name"`
+	TeamID              string `json:"team_id"`
+	NumLicensedUsers    int    `json:"num_licensed_users"`
+	NumProvisionedUsers int    `json:"num_provisioned_users"`
+	NumUsedLicenses     int    `json:"num_used_licenses"`
+}
+
+// GetFeatureValuesBody is the request body of team/features/get_values.
+type GetFeatureValuesBody struct {
+	Features []Tag `json:"features"`
+}
+
+// GetFeatureValuesPayload is the response of team/features/get_values.
+type GetFeatureValuesPayload struct {
+	Values []FeatureValue `json:"values"`
+}
+
+// FeatureValue is one team feature's value. Dropbox wraps each value in a
+// union tagged with the feature name, e.g.
+//
+//	{".tag": "has_team_shared_dropbox", "has_team_shared_dropbox": {".tag": "has_team_shared_dropbox", "has_team_shared_dropbox": true}}
+//	{".tag": "upload_api_rate_limit", "upload_api_rate_limit": {".tag": "limit", "limit": 25000}}
+//
+// Value is the inner union's field named by its tag (true, 25000) or, for
+// tags without a field such as "unlimited", the tag itself.
+type FeatureValue struct {
+	Feature string
+	Value   any
+}
+
+func (f *FeatureValue) UnmarshalJSON(data []byte) error {
+	var outer map[string]json.RawMessage
+	if err := json.Unmarshal(data, &outer); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(outer[".tag"], &f.Feature); err != nil {
+		return err
+	}
+
+	var inner map[string]json.RawMessage
+	if raw, ok := outer[f.Feature]; !ok || json.Unmarshal(raw, &inner) != nil {
+		f.Value = nil
 		return nil
 	}
+	var tag string
+	if err := json.Unmarshal(inner[".tag"], &tag); err != nil {
+		return err
+	}
+	if raw, ok := inner[tag]; ok {
+		return json.Unmarshal(raw, &f.Value)
+	}
+	f.Value = tag
+	return nil
 }
 
-// UserLogInfo identifies the team member associated with a team log event.
-type UserLogInfo struct {
-	AccountID    string `json:"account_id"`
-	TeamMemberID string `json:"team_member_id"`
-	Email        string `json:"email"`
-	DisplayName  string `json:"display_name"`
+// ListMembersDevicesBody is the request body of team/devices/list_members_devices.
+type ListMembersDevicesBody struct {
+	Cursor                string `json:"cursor,omitempty"`
+	IncludeWebSessions    bool   `json:"include_web_sessions"`
+	IncludeDesktopClients bool   `json:"include_desktop_clients"`
+	IncludeMobileClients  bool   `json:"include_mobile_clients"`
+}
+
+// ListMembersDevicesPayload is the response of team/devices/list_members_devices.
+type ListMembersDevicesPayload struct {
+	Devices []MemberDevices `json:"devices"`
+	Cursor  string          `json:"cursor"`
+	HasMore bool            `json:"has_more"`
+}
+
+// MemberDevices are a team member's linked devices.
+type MemberDevices struct {
+	TeamMemberID   string          `json:"team_member_id"`
+	WebSessions    []DeviceSession `json:"web_sessions"`
+	DesktopClients []DeviceSession `json:"desktop_clients"`
+	MobileClients  []DeviceSession `json:"mobile_clients"`
+}
+
+// DeviceSession holds the fields shared by web sessions, desktop clients and
+// mobile clients. Updated is when Dropbox last saw activity from the session.
+type DeviceSession struct {
+	SessionID string `json:"session_id"`
+	IPAddress string `json:"ip_address,omitempty"`
+	Country   string `json:"country,omitempty"`
+	Created   string `json:"created,omitempty"`
+	Updated   string `json:"updated,omitempty"`
+}
+
+// AuthenticatedAdminPayload is the response of team/token/get_authenticated_admin.
+type AuthenticatedAdminPayload struct {
+	AdminProfile Profile `json:"admin_profile"`
+}
+
+// ListNamespacesBody is the request body of team/namespaces/list.
+type ListNamespacesBody struct {
+	Limit int `json:"limit,omitempty"`
+}
+
+// ListNamespacesContinueBody is the request body of
+// team/namespaces/list/continue.
+type ListNamespacesContinueBody struct {
+	Cursor string `json:"cursor"`
+}
+
+// ListNamespacesPayload is the response of team/namespaces/list and its
+// continuation.
+type ListNamespacesPayload struct {
+	Namespaces []NamespaceMetadata `json:"namespaces"`
+	Cursor     string              `json:"cursor"`
+	HasMore    bool                `json:"has_more"`
+}
+
+// NamespaceMetadata describes a team namespace. NamespaceType's tag is
+// app_folder, shared_folder, team_folder or team_member_folder; TeamMemberID
+// is set for the folders owned by a member.
+type NamespaceMetadata struct {
+	Name          string `json:"name"`
+	NamespaceID   string `json:"namespace_id"`
+	NamespaceType Tag    `json:"namespace_type"`
+	TeamMemberID  string `json:"team_member_id,omitempty"`
 }
@@ -1,99 +1,53 @@
 package dropbox
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
-	"github.com/conductorone/baton-sdk/pkg/uhttp"
 )
 
-func (c *Client) AddRoleToUser(ctx context.Context, roleId string, teamMemberID string) (*v2.RateLimitDescription, error) {
-	token, err := c.TokenSource.Token()
+// GetAvailableRoles returns the admin roles that can be assigned to team
+// members.
+// Based on API: POST /2/team/members/get_available_team_member_roles.
+func (c *Client) GetAvailableRoles(ctx context.Context) ([]Role, *v2.RateLimitDescription, error) {
+	var result AvailableRolesPayload
+	annos, err := c.doRequest(ctx, c.url("/2/team/members/get_available_team_member_roles"), http.MethodPost, &result, nil)
 	if err != nil {
-		return nil, err
+		return nil, getRateLimitFromAnnos(annos), fmt.Errorf("failed to get available roles: %w", err)
 	}
 
+	return result.Roles, getRateLimitFromAnnos(annos), nil
+}
+
+func (c *Client) AddRoleToUser(ctx context.Context, roleId string, teamMemberID string) (*v2.RateLimitDescription, error) {
 	body := addRoleToUserBody{
 		NewRoles:   []string{roleId},
 		TeamMember: TeamMemberIdTag{Tag: "team_member_id", TeamMemberID: teamMemberID},
 	}
 
-	buffer := new(bytes.Buffer)
-	err = json.NewEncoder(buffer).Encode(body)
-	if err != nil {
-		return nil, err
-	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url("/2/team/members/set_admin_permissions_v2"), buffer)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token.AccessToken))
-	req.Header.Set("Content-Type", "application/json")
-
-	var ratelimitData v2.RateLimitDescription
-	res, err := c.wrapper.Do(req,
-		uhttp.WithRatelimitData(&ratelimitData),
-	)
-
+	annos, err := c.doRequest(ctx, c.url("/2/team/members/set_admin_permissions_v2"), http.MethodPost, nil, body)
 	if err != nil {
-		logBody(ctx, res)
-		return &ratelimitData, err
-	}
-
-	defer res.Body.Close()
-	if res.StatusCode != http.StatusOK {
-		logBody(ctx, res)
-		return &ratelimitData, err
+		return getRateLimitFromAnnos(annos), fmt.Errorf("failed to add role to user: %w", err)
 	}
 
-	return &ratelimitData, nil
+	return getRateLimitFromAnnos(annos), nil
 }
 
 // endpoint only allows removing all roles, not specific roles
 // also removing them all leaves the user with the member role by default
 // https://www.dropbox.com/developers/documentation/http/teams#team-members-set_admin_permissions
 func (c *Client) ClearRoles(ctx context.Context, teamMemberID string) (*v2.RateLimitDescription, error) {
-	token, err := c.TokenSource.Token()
-	if err != nil {
-		return nil, err
-	}
-
 	body := addRoleToUserBody{
 		NewRoles:   []string{},
 		TeamMember: TeamMemberIdTag{Tag: "team_member_id", TeamMemberID: teamMemberID},
 	}
 
-	buffer := new(bytes.Buffer)
-	err = json.NewEncoder(buffer).Encode(body)
+	annos, err := c.doRequest(ctx, c.url("/2/team/members/set_admin_permissions_v2"), http.MethodPost, nil, body)
 	if err != nil {
-		return nil, err
-	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url("/2/team/members/set_admin_permissions_v2"), buffer)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token.AccessToken))
-	req.Header.Set("Content-Type", "application/json")
-
-	var ratelimitData v2.RateLimitDescription
-	res, err := c.wrapper.Do(req,
-		uhttp.WithRatelimitData(&ratelimitData),
-	)
-
-	if err != nil {
-		logBody(ctx, res)
-		return &ratelimitData, err
-	}
-
-	defer res.Body.Close()
-	if res.StatusCode != http.StatusOK {
-		logBody(ctx, res)
-		return &ratelimitData, err
+		return getRateLimitFromAnnos(annos), fmt.Errorf("failed to clear roles: %w", err)
 	}
 
-	return &ratelimitData, nil
+	return getRateLimitFromAnnos(annos), nil
 }
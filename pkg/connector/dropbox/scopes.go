@@ -0,0 +1,64 @@
+package dropbox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// scopeProbes maps read scopes to a cheap request that Dropbox rejects with
+// missing_scope when the scope wasn't granted. Write scopes have no
+// side-effect-free probe.
+var scopeProbes = map[string]struct {
+	path string
+	body any
+}{
+	"team_info.read":   {path: "/2/team/get_info"},
+	"members.read":     {path: "/2/team/members/list_v2", body: map[string]int{"limit": 1}},
+	"groups.read":      {path: "/2/team/groups/list", body: map[string]int{"limit": 1}},
+	"events.read":      {path: "/2/team_log/get_events", body: map[string]int{"limit": 1}},
+	"sessions.list":    {path: "/2/team/devices/list_members_devices", body: ListMembersDevicesBody{}},
+	"team_data.member": {path: "/2/team/namespaces/list", body: ListNamespacesBody{Limit: 1}},
+}
+
+// CheckScopes reports which of scopes the app was not granted. Granted scopes
+// are read from the token when Dropbox reported them on refresh; otherwise
+// read scopes are probed against the API and any scope that can't be checked
+// is returned as unverified.
+func (c *Client) CheckScopes(ctx context.Context, scopes []string) (missing []string, unverified []string, err error) {
+	token, err := c.TokenSource.Token()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get token: %w", err)
+	}
+
+	if granted, ok := token.Extra("scope").(string); ok && granted != "" {
+		grantedScopes := strings.Fields(granted)
+		for _, scope := range scopes {
+			if !slices.Contains(grantedScopes, scope) {
+				missing = append(missing, scope)
+			}
+		}
+		return missing, nil, nil
+	}
+
+	for _, scope := range scopes {
+		probe, ok := scopeProbes[scope]
+		if !ok {
+			unverified = append(unverified, scope)
+			continue
+		}
+
+		_, err := c.doRequestOnce(ctx, token, c.url(probe.path), http.MethodPost, nil, probe.body, nil)
+		switch {
+		case err == nil:
+		case HasErrorTag(err, "missing_scope"):
+			missing = append(missing, scope)
+		default:
+			return nil, nil, fmt.Errorf("failed to check scope %s: %w", scope, err)
+		}
+	}
+
+	return missing, unverified, nil
+}
@@ -0,0 +1,52 @@
+package dropbox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// GetTeamInfo returns information about the team the token belongs to.
+// Based on API: POST /2/team/get_info.
+func (c *Client) GetTeamInfo(ctx context.Context) (*TeamInfoPayload, *v2.RateLimitDescription, error) {
+	var result TeamInfoPayload
+	annos, err := c.doRequest(ctx, c.url("/2/team/get_info"), http.MethodPost, &result, nil)
+	if err != nil {
+		return nil, getRateLimitFromAnnos(annos), fmt.Errorf("failed to get team info: %w", err)
+	}
+
+	return &result, getRateLimitFromAnnos(annos), nil
+}
+
+// GetAuthenticatedAdmin returns the profile of the team admin who authorized
+// the app. It fails for team tokens not linked to an admin.
+// Based on API: POST /2/team/token/get_authenticated_admin.
+func (c *Client) GetAuthenticatedAdmin(ctx context.Context) (*Profile, *v2.RateLimitDescription, error) {
+	var result AuthenticatedAdminPayload
+	annos, err := c.doRequest(ctx, c.url("/2/team/token/get_authenticated_admin"), http.MethodPost, &result, nil)
+	if err != nil {
+		return nil, getRateLimitFromAnnos(annos), fmt.Errorf("failed to get authenticated admin: %w", err)
+	}
+
+	return &result.AdminProfile, getRateLimitFromAnnos(annos), nil
+}
+
+// GetFeatureValues returns the values of features, e.g.
+// "upload_api_rate_limit", for the team.
+// Based on API: POST /2/team/features/get_values.
+func (c *Client) GetFeatureValues(ctx context.Context, features []string) ([]FeatureValue, *v2.RateLimitDescription, error) {
+	body := GetFeatureValuesBody{Features: make([]Tag, 0, len(features))}
+	for _, feature := range features {
+		body.Features = append(body.Features, Tag{Tag: feature})
+	}
+
+	var result GetFeatureValuesPayload
+	annos, err := c.doRequest(ctx, c.url("/2/team/features/get_values"), http.MethodPost, &result, body)
+	if err != nil {
+		return nil, getRateLimitFromAnnos(annos), fmt.Errorf("failed to get team feature values: %w", err)
+	}
+
+	return result.Values, getRateLimitFromAnnos(annos), nil
+}
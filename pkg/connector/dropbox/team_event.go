@@ -0,0 +1,392 @@
+package dropbox
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// TeamEvent represents a single entry in the Dropbox team event audit log.
+// Each union in it (actor, context, participants, assets, origin access
+// method and details) keeps its tag and the JSON it was decoded from, so
+// variants that aren't modeled are passed through rather than dropped.
+type TeamEvent struct {
+	Timestamp            string               `json:"timestamp"`
+	EventCategory        Tag                  `json:"event_category"`
+	EventType            Tag                  `json:"event_type"`
+	Actor                ActorLogInfo         `json:"actor"`
+	Origin               *OriginLogInfo       `json:"origin,omitempty"`
+	InvolveNonTeamMember bool                 `json:"involve_non_team_member,omitempty"`
+	Context              *ContextLogInfo      `json:"context,omitempty"`
+	Participants         []ParticipantLogInfo `json:"participants,omitempty"`
+	Assets               []AssetLogInfo       `json:"assets,omitempty"`
+	Details              *EventDetails        `json:"details,omitempty"`
+
+	// raw is the event as Dropbox sent it, for ID.
+	raw json.RawMessage
+}
+
+func (e *TeamEvent) UnmarshalJSON(data []byte) error {
+	type teamEvent TeamEvent
+	if err := json.Unmarshal(data, (*teamEvent)(e)); err != nil {
+		return err
+	}
+	e.raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// ID returns an identifier derived from the event's content. Dropbox documents
+// no event ID; two entries get the same ID only if everything about them,
+// including the timestamp, actor, origin and details, is the same.
+func (e *TeamEvent) ID() string {
+	data := e.raw
+	if data == nil {
+		// Events built rather than decoded hash their encoding instead.
+		data, _ = json.Marshal(e)
+	}
+
+	// Re-encode through a generic value so key order and whitespace don't
+	// change the ID.
+	var content any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&content); err == nil {
+		if canonical, err := json.Marshal(content); err == nil {
+			data = canonical
+		}
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// DecodeDetails decodes the event's details into v, e.g. a
+// *GroupMemberDetails for group_add_member events.
+func (e *TeamEvent) DecodeDetails(v any) error {
+	if e.Details == nil {
+		return fmt.Errorf("%s event has no details", e.EventType.Tag)
+	}
+	return e.Details.Decode(v)
+}
+
+// Group returns the group the event happened to, or nil if no group took
+// part in it.
+func (e *TeamEvent) Group() *GroupLogInfo {
+	for i := range e.Participants {
+		if e.Participants[i].Tag == "group" && e.Participants[i].GroupID != "" {
+			return &e.Participants[i].GroupLogInfo
+		}
+	}
+	return nil
+}
+
+// unmarshalUnion decodes data into v, an alias of a union type without its
+// UnmarshalJSON, and keeps data in raw.
+func unmarshalUnion(data []byte, v any, raw *json.RawMessage) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	*raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// EventDetails is the EventDetails union, whose variant is named after the
+// event type (e.g. "group_add_member_details"). Variants are decoded on demand
+// into the struct for the event type with Decode; Raw holds the variant as
+// sent. Only the details the connector reads are modeled below; Dropbox
+// defines several hundred variants and the rest are only available as Raw.
+type EventDetails struct {
+	Tag string
+	Raw json.RawMessage
+}
+
+func (d *EventDetails) UnmarshalJSON(data []byte) error {
+	var tag Tag
+	if err := json.Unmarshal(data, &tag); err != nil {
+		return err
+	}
+	d.Tag = tag.Tag
+	d.Raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+func (d EventDetails) MarshalJSON() ([]byte, error) {
+	if d.Raw == nil {
+		return json.Marshal(Tag{Tag: d.Tag})
+	}
+	return d.Raw, nil
+}
+
+// Decode decodes the details into v.
+func (d *EventDetails) Decode(v any) error {
+	if len(d.Raw) == 0 {
+		return fmt.Errorf("%s details are empty", d.Tag)
+	}
+	return json.Unmarshal(d.Raw, v)
+}
+
+// GroupMemberDetails are the details of group_add_member and
+// group_change_member_role events. IsGroupOwner is the member's new access.
+type GroupMemberDetails struct {
+	IsGroupOwner bool `json:"is_group_owner"`
+}
+
+// AdminRoleDetails are the details of member_change_admin_role events. The
+// values are AdminRole tags, e.g. "team_admin"; a nil value means the member
+// had, or now has, no admin role.
+type AdminRoleDetails struct {
+	NewValue      *Tag `json:"new_value,omitempty"`
+	PreviousValue *Tag `json:"previous_value,omitempty"`
+}
+
+// LoginDetails are the details of login_success, login_fail, emm_error and
+// sso_error events. LoginMethod is a LoginMethod tag, e.g. "password" or
+// "saml"; ErrorDetails is only set on failures.
+type LoginDetails struct {
+	IsEmmManaged bool                   `json:"is_emm_managed,omitempty"`
+	LoginMethod  *Tag                   `json:"login_method,omitempty"`
+	ErrorDetails *FailureDetailsLogInfo `json:"error_details,omitempty"`
+}
+
+// TfaChangeStatusDetails are the details of tfa_change_status events. The
+// values are TfaConfiguration tags: "disabled", "enabled", "sms" or
+// "authenticator".
+type TfaChangeStatusDetails struct {
+	NewValue       Tag  `json:"new_value"`
+	PreviousValue  *Tag `json:"previous_value,omitempty"`
+	UsedRescueCode bool `json:"used_rescue_code,omitempty"`
+}
+
+// PolicyChangeDetails are the details of most team_policies events, which
+// record a policy's new value and, for most policies, its previous one.
+type PolicyChangeDetails struct {
+	NewValue      *PolicyValue `json:"new_value,omitempty"`
+	PreviousValue *PolicyValue `json:"previous_value,omitempty"`
+}
+
+// PolicyValue is a policy value rendered as text. Depending on the policy,
+// values are unions, rendered as their tag followed by any other fields as
+// JSON, or strings and numbers, rendered as written.
+type PolicyValue string
+
+func (v *PolicyValue) UnmarshalJSON(data []byte) error {
+	var union map[string]json.RawMessage
+	if err := json.Unmarshal(data, &union); err == nil && union != nil {
+		var tag string
+		if raw, ok := union[".tag"]; ok {
+			if err := json.Unmarshal(raw, &tag); err != nil {
+				return err
+			}
+			delete(union, ".tag")
+		}
+		if len(union) == 0 {
+			*v = PolicyValue(tag)
+			return nil
+		}
+		// Map keys marshal sorted, so the rendering is stable.
+		fields, err := json.Marshal(union)
+		if err != nil {
+			return err
+		}
+		*v = PolicyValue(strings.TrimSpace(tag + " " + string(fields)))
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*v = PolicyValue(text)
+		return nil
+	}
+	*v = PolicyValue(bytes.TrimSpace(data))
+	return nil
+}
+
+// FailureDetailsLogInfo describes why a sign-in failed.
+type FailureDetailsLogInfo struct {
+	UserFriendlyMessage   string `json:"user_friendly_message,omitempty"`
+	TechnicalErrorMessage string `json:"technical_error_message,omitempty"`
+}
+
+// ActorLogInfo represents the entity who performed a team log event: a team
+// member ("user" or "admin", both wrapping a UserLogInfo — Dropbox logs a team
+// member's own actions under "admin" instead of "user" when that member has
+// admin permissions), an "app", a "reseller", or "dropbox" or "anonymous",
+// which carry no fields.
+type ActorLogInfo struct {
+	Tag   string       `json:".tag"`
+	User  *UserLogInfo `json:"user,omitempty"`
+	Admin *UserLogInfo `json:"admin,omitempty"`
+	App   *AppLogInfo  `json:"app,omitempty"`
+	ResellerLogInfo
+
+	Raw json.RawMessage `json:"-"`
+}
+
+func (a *ActorLogInfo) UnmarshalJSON(data []byte) error {
+	type actor ActorLogInfo
+	return unmarshalUnion(data, (*actor)(a), &a.Raw)
+}
+
+// UserLogInfo returns the team member behind this event, whether they acted
+// as a plain "user" or as an "admin" (Dropbox logs a team member's own
+// actions under "admin" instead of "user" when that member has admin
+// permissions). Returns nil for any other actor kind (app, dropbox,
+// anonymous, reseller).
+func (a ActorLogInfo) UserInfo() *UserLogInfo {
+	switch a.Tag {
+	case "user":
+		return a.User
+	case "admin":
+		return a.Admin
+	default:
+		return nil
+	}
+}
+
+// UserLogInfo identifies the user associated with a team log event. Tag is
+// "team_member", "trusted_non_team_member" or "non_team_member"; only team
+// members have a TeamMemberID.
+type UserLogInfo struct {
+	Tag          string `json:".tag,omitempty"`
+	AccountID    string `json:"account_id"`
+	TeamMemberID string `json:"team_member_id"`
+	Email        string `json:"email"`
+	DisplayName  string `json:"display_name"`
+}
+
+// AppLogInfo identifies an app acting in a team log event. Tag is
+// "user_or_team_linked_app", "user_linked_app" or "team_linked_app".
+type AppLogInfo struct {
+	Tag         string `json:".tag"`
+	AppID       string `json:"app_id,omitempty"`
+	DisplayName string `json:"display_name,omitempty"`
+}
+
+// ResellerLogInfo identifies a reseller acting in a team log event.
+type ResellerLogInfo struct {
+	ResellerName  string `json:"reseller_name,omitempty"`
+	ResellerEmail string `json:"reseller_email,omitempty"`
+}
+
+// OriginLogInfo is where an event came from.
+type OriginLogInfo struct {
+	GeoLocation  *GeoLocationLogInfo `json:"geo_location,omitempty"`
+	AccessMethod AccessMethodLogInfo `json:"access_method"`
+}
+
+// GeoLocationLogInfo is the IP address an event came from and where Dropbox
+// places it.
+type GeoLocationLogInfo struct {
+	City      string `json:"city,omitempty"`
+	Region    string `json:"region,omitempty"`
+	Country   string `json:"country,omitempty"`
+	IPAddress string `json:"ip_address"`
+}
+
+// AccessMethodLogInfo is how the actor reached Dropbox: as an "end_user" of a
+// web, desktop or mobile session, through "sign_in_as" another member, the
+// "content_manager", "admin_console" or "enterprise_console", or the "api".
+// Only end_user nests its session under the tag's name; the other variants
+// are structs whose fields sit next to the tag, so SessionID is set for
+// sign_in_as and the consoles and RequestID for api.
+type AccessMethodLogInfo struct {
+	Tag       string          `json:".tag"`
+	EndUser   *SessionLogInfo `json:"end_user,omitempty"`
+	SessionID string          `json:"session_id,omitempty"`
+	RequestID string          `json:"request_id,omitempty"`
+
+	Raw json.RawMessage `json:"-"`
+}
+
+func (m *AccessMethodLogInfo) UnmarshalJSON(data []byte) error {
+	type accessMethod AccessMethodLogInfo
+	return unmarshalUnion(data, (*accessMethod)(m), &m.Raw)
+}
+
+// SessionLogInfo identifies a session. Tag is "web", "desktop" or "mobile"
+// where the session kind is known.
+type SessionLogInfo struct {
+	Tag       string `json:".tag,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+}
+
+// ContextLogInfo is the entity an event happened to, e.g. the member whose
+// status changed: a "team_member", "non_team_member" or
+// "trusted_non_team_member", which inline their UserLogInfo fields, or the
+// "team", an "organization_team" or "anonymous".
+type ContextLogInfo struct {
+	Tag string `json:".tag"`
+	UserLogInfo
+
+	Raw json.RawMessage `json:"-"`
+}
+
+func (c *ContextLogInfo) UnmarshalJSON(data []byte) error {
+	type contextLogInfo ContextLogInfo
+	return unmarshalUnion(data, (*contextLogInfo)(c), &c.Raw)
+}
+
+// TeamMember returns the team member the event happened to, or nil if the
+// context isn't a team member.
+func (c *ContextLogInfo) TeamMember() *UserLogInfo {
+	if c == nil || c.Tag != "team_member" || c.TeamMemberID == "" {
+		return nil
+	}
+	return &c.UserLogInfo
+}
+
+// ParticipantLogInfo is a user or group affected by an event. The "user"
+// variant nests a UserLogInfo; the "group" variant inlines a GroupLogInfo.
+type ParticipantLogInfo struct {
+	Tag  string       `json:".tag"`
+	User *UserLogInfo `json:"user,omitempty"`
+	GroupLogInfo
+
+	Raw json.RawMessage `json:"-"`
+}
+
+func (p *ParticipantLogInfo) UnmarshalJSON(data []byte) error {
+	type participant ParticipantLogInfo
+	return unmarshalUnion(data, (*participant)(p), &p.Raw)
+}
+
+// GroupLogInfo identifies a group in a team log event.
+type GroupLogInfo struct {
+	GroupID     string `json:"group_id,omitempty"`
+	DisplayName string `json:"display_name,omitempty"`
+}
+
+// AssetLogInfo is an asset an event touched. The "file" and "folder" variants
+// carry a path; Paper and showcase variants are kept in Raw only.
+type AssetLogInfo struct {
+	Tag         string       `json:".tag"`
+	Path        *PathLogInfo `json:"path,omitempty"`
+	DisplayName string       `json:"display_name,omitempty"`
+	FileID      string       `json:"file_id,omitempty"`
+
+	Raw json.RawMessage `json:"-"`
+}
+
+func (a *AssetLogInfo) UnmarshalJSON(data []byte) error {
+	type asset AssetLogInfo
+	return unmarshalUnion(data, (*asset)(a), &a.Raw)
+}
+
+// PathLogInfo is the path of an asset, as seen by the actor (Contextual) and
+// relative to the namespace holding it.
+type PathLogInfo struct {
+	Contextual        string                       `json:"contextual,omitempty"`
+	NamespaceRelative NamespaceRelativePathLogInfo `json:"namespace_relative"`
+}
+
+// NamespaceRelativePathLogInfo locates an asset within a namespace. Shared
+// namespaces are team folders and shared folders; the rest are members' own
+// folders.
+type NamespaceRelativePathLogInfo struct {
+	NsID              string `json:"ns_id,omitempty"`
+	RelativePath      string `json:"relative_path,omitempty"`
+	IsSharedNamespace bool   `json:"is_shared_namespace,omitempty"`
+}
@@ -0,0 +1,92 @@
+package dropbox
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const appEventJSON = `{
+	"timestamp": "2024-01-01T10:00:00Z",
+	"event_category": {".tag": "apps"},
+	"event_type": {".tag": "app_link_team"},
+	"actor": {".tag": "app", "app": {".tag": "team_linked_app", "app_id": "dbaid:1", "display_name": "Backup"}},
+	"origin": {
+		"geo_location": {"city": "Lisbon", "region": "Lisboa", "country": "PT", "ip_address": "192.0.2.1"},
+		"access_method": {".tag": "end_user", "end_user": {".tag": "web", "session_id": "dbwsid:1"}}
+	},
+	"context": {".tag": "team"},
+	"assets": [{".tag": "paper_document", "doc_id": "doc:1", "doc_title": "Plan"}],
+	"details": {".tag": "app_link_team_details", "app_info": {".tag": "team_linked_app", "app_id": "dbaid:1"}}
+}`
+
+func TestTeamEvent_DecodesActorsOriginAndUnknownVariants(t *testing.T) {
+	var e TeamEvent
+	require.NoError(t, json.Unmarshal([]byte(appEventJSON), &e))
+
+	require.Equal(t, "app", e.Actor.Tag)
+	require.Nil(t, e.Actor.UserInfo())
+	require.Equal(t, "dbaid:1", e.Actor.App.AppID)
+
+	require.Equal(t, "PT", e.Origin.GeoLocation.Country)
+	require.Equal(t, "192.0.2.1", e.Origin.GeoLocation.IPAddress)
+	require.Equal(t, "end_user", e.Origin.AccessMethod.Tag)
+	require.Equal(t, "web", e.Origin.AccessMethod.EndUser.Tag)
+
+	// Variants without a model keep their tag and JSON.
+	require.Equal(t, "paper_document", e.Assets[0].Tag)
+	require.JSONEq(t, `{".tag": "paper_document", "doc_id": "doc:1", "doc_title": "Plan"}`, string(e.Assets[0].Raw))
+	require.Equal(t, "app_link_team_details", e.Details.Tag)
+
+	var details struct {
+		AppInfo AppLogInfo `json:"app_info"`
+	}
+	require.NoError(t, e.DecodeDetails(&details))
+	require.Equal(t, "dbaid:1", details.AppInfo.AppID)
+}
+
+func TestAccessMethodLogInfo_DecodesStructVariantsFromTheTopLevel(t *testing.T) {
+	decode := func(body string) AccessMethodLogInfo {
+		var m AccessMethodLogInfo
+		require.NoError(t, json.Unmarshal([]byte(body), &m))
+		return m
+	}
+
+	for _, tag := range []string{"admin_console", "content_manager", "enterprise_console", "sign_in_as"} {
+		m := decode(`{".tag": "` + tag + `", "session_id": "dbwsid:2"}`)
+		require.Equal(t, tag, m.Tag)
+		require.Equal(t, "dbwsid:2", m.SessionID)
+		require.Nil(t, m.EndUser)
+	}
+
+	api := decode(`{".tag": "api", "request_id": "dbarid:1"}`)
+	require.Equal(t, "dbarid:1", api.RequestID)
+
+	endUser := decode(`{".tag": "end_user", "end_user": {".tag": "desktop", "session_id": "dbdsid:1"}}`)
+	require.Equal(t, "desktop", endUser.EndUser.Tag)
+	require.Equal(t, "dbdsid:1", endUser.EndUser.SessionID)
+	require.Empty(t, endUser.SessionID)
+}
+
+func TestTeamEvent_ID(t *testing.T) {
+	login := func(body string) *TeamEvent {
+		var e TeamEvent
+		require.NoError(t, json.Unmarshal([]byte(body), &e))
+		return &e
+	}
+
+	fromLisbon := login(`{"timestamp": "2024-01-01T10:00:00Z", "event_type": {".tag": "login_success"},
+		"actor": {".tag": "user", "user": {".tag": "team_member", "team_member_id": "dbmid:1"}},
+		"origin": {"geo_location": {"ip_address": "192.0.2.1"}, "access_method": {".tag": "end_user"}}}`)
+	reordered := login(`{"origin": {"access_method": {".tag": "end_user"}, "geo_location": {"ip_address": "192.0.2.1"}},
+		"actor": {"user": {"team_member_id": "dbmid:1", ".tag": "team_member"}, ".tag": "user"},
+		"event_type": {".tag": "login_success"}, "timestamp": "2024-01-01T10:00:00Z"}`)
+	fromElsewhere := login(`{"timestamp": "2024-01-01T10:00:00Z", "event_type": {".tag": "login_success"},
+		"actor": {".tag": "user", "user": {".tag": "team_member", "team_member_id": "dbmid:1"}},
+		"origin": {"geo_location": {"ip_address": "198.51.100.7"}, "access_method": {".tag": "end_user"}}}`)
+
+	require.Len(t, fromLisbon.ID(), 32)
+	require.Equal(t, fromLisbon.ID(), reordered.ID())
+	require.NotEqual(t, fromLisbon.ID(), fromElsewhere.ID(), "same-second sign-ins by one member must not collide")
+}
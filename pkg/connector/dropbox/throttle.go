@@ -0,0 +1,205 @@
+package dropbox
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"golang.org/x/time/rate"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// EndpointClass groups endpoints that share a client-side rate limit.
+type EndpointClass string
+
+const (
+	// EndpointClassRead covers list and get endpoints.
+	EndpointClassRead EndpointClass = "reads"
+	// EndpointClassWrite covers endpoints that change team state.
+	EndpointClassWrite EndpointClass = "writes"
+	// EndpointClassTeamLog covers the team_log event endpoints, which Dropbox
+	// limits separately and more strictly.
+	EndpointClassTeamLog EndpointClass = "team_log"
+)
+
+// RateLimit is a token bucket: RequestsPerMinute tokens are added per minute,
+// up to Burst.
+type RateLimit struct {
+	RequestsPerMinute int
+	Burst             int
+}
+
+// DefaultRateLimits are used for classes without a configured limit. Dropbox
+// doesn't publish its limits; these stay well below the point where it starts
+// returning too_many_requests for a single app.
+var DefaultRateLimits = map[EndpointClass]RateLimit{
+	EndpointClassRead:    {RequestsPerMinute: 600, Burst: 10},
+	EndpointClassWrite:   {RequestsPerMinute: 120, Burst: 2},
+	EndpointClassTeamLog: {RequestsPerMinute: 300, Burst: 5},
+}
+
+const (
+	// defaultMaxRetries is how many times a throttled request is retried.
+	defaultMaxRetries = 4
+	// retryBaseDelay is the first backoff delay when Dropbox doesn't say how
+	// long to wait; it doubles on each retry up to retryMaxDelay.
+	retryBaseDelay = time.Second
+	retryMaxDelay  = time.Minute
+)
+
+// endpointClass classifies an endpoint by its URL path.
+func endpointClass(path string) EndpointClass {
+	switch {
+	case strings.HasPrefix(path, "/2/team_log/"):
+		return EndpointClassTeamLog
+	case strings.Contains(path, "/list") || strings.Contains(path, "/get_"):
+		return EndpointClassRead
+	default:
+		return EndpointClassWrite
+	}
+}
+
+// throttle holds a Client's per-class token buckets and per-namespace write
+// locks.
+type throttle struct {
+	mu         sync.Mutex
+	limits     map[EndpointClass]RateLimit
+	limiters   map[EndpointClass]*rate.Limiter
+	namespaces map[string]*sync.Mutex
+}
+
+func newThrottle(limits map[EndpointClass]RateLimit) *throttle {
+	t := &throttle{
+		limits:     map[EndpointClass]RateLimit{},
+		limiters:   map[EndpointClass]*rate.Limiter{},
+		namespaces: map[string]*sync.Mutex{},
+	}
+	for class, limit := range DefaultRateLimits {
+		t.set(class, limit)
+	}
+	for class, limit := range limits {
+		t.set(class, limit)
+	}
+	return t
+}
+
+// set replaces class's limit. Zero fields keep the current value.
+func (t *throttle) set(class EndpointClass, limit RateLimit) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	current := t.limits[class]
+	if limit.RequestsPerMinute <= 0 {
+		limit.RequestsPerMinute = current.RequestsPerMinute
+	}
+	if limit.Burst <= 0 {
+		limit.Burst = max(current.Burst, 1)
+	}
+	t.limits[class] = limit
+	t.limiters[class] = rate.NewLimiter(rate.Limit(float64(limit.RequestsPerMinute)/60), limit.Burst)
+}
+
+func (t *throttle) limiter(class EndpointClass) (*rate.Limiter, RateLimit) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.limiters[class], t.limits[class]
+}
+
+// wait blocks until class's bucket has a token or ctx is done.
+func (t *throttle) wait(ctx context.Context, class EndpointClass) error {
+	limiter, _ := t.limiter(class)
+	return limiter.Wait(ctx)
+}
+
+// namespaceLock returns the lock serializing writes to namespace.
+func (t *throttle) namespaceLock(namespace string) *sync.Mutex {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lock, ok := t.namespaces[namespace]
+	if !ok {
+		lock = &sync.Mutex{}
+		t.namespaces[namespace] = lock
+	}
+	return lock
+}
+
+// describe fills in description from class's bucket when Dropbox didn't send
+// rate limit headers, which it only does on 429s.
+func (t *throttle) describe(class EndpointClass, description *v2.RateLimitDescription) {
+	if description.GetLimit() != 0 || description.GetStatus() == v2.RateLimitDescription_STATUS_OVERLIMIT {
+		return
+	}
+
+	limiter, limit := t.limiter(class)
+	tokens := max(limiter.Tokens(), 0)
+	refill := time.Duration((float64(limit.Burst) - tokens) / float64(limiter.Limit()) * float64(time.Second))
+
+	description.SetLimit(int64(limit.Burst))
+	description.SetRemaining(int64(tokens))
+	description.SetResetAt(timestamppb.New(time.Now().Add(refill)))
+	if int64(tokens) > 0 {
+		description.SetStatus(v2.RateLimitDescription_STATUS_OK)
+	} else {
+		description.SetStatus(v2.RateLimitDescription_STATUS_OVERLIMIT)
+	}
+}
+
+// requestNamespace identifies the namespace a request writes to, from its
+// path root or selected user; team-level requests share "".
+func requestNamespace(header map[string]string) string {
+	if pathRoot, ok := header["Dropbox-API-Path-Root"]; ok {
+		return "root:" + pathRoot
+	}
+	if member, ok := header["Dropbox-API-Select-User"]; ok {
+		return "member:" + member
+	}
+	if admin, ok := header["Dropbox-API-Select-Admin"]; ok {
+		return "admin:" + admin
+	}
+	return ""
+}
+
+// SetRateLimits overrides the client-side rate limits for the given endpoint
+// classes. Zero fields keep the current value.
+func (c *Client) SetRateLimits(limits map[EndpointClass]RateLimit) {
+	for class, limit := range limits {
+		c.throttle.set(class, limit)
+	}
+}
+
+// retryDelay reports whether a request that failed with err should be
+// retried and, if so, how long to wait after attempt (counting from 1).
+// Dropbox's Retry-After is treated as a minimum; jitter is added on top so
+// concurrent callers don't retry in lockstep.
+func (c *Client) retryDelay(err error, attempt int) (time.Duration, bool) {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return 0, false
+	}
+
+	if !isRateLimited(err) && apiErr.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+
+	delay := min(c.retryBase<<(attempt-1), retryMaxDelay)
+	delay = max(delay, apiErr.RetryAfter)
+	return delay + rand.N(delay/2+1), true
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
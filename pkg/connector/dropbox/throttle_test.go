@@ -0,0 +1,185 @@
+package dropbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndpointClass(t *testing.T) {
+	require.Equal(t, EndpointClassTeamLog, endpointClass("/2/team_log/get_events/continue"))
+	require.Equal(t, EndpointClassRead, endpointClass("/2/team/members/list/continue_v2"))
+	require.Equal(t, EndpointClassRead, endpointClass("/2/team/members/get_info_v2"))
+	require.Equal(t, EndpointClassRead, endpointClass("/2/users/get_space_usage"))
+	require.Equal(t, EndpointClassWrite, endpointClass("/2/team/groups/members/add"))
+	require.Equal(t, EndpointClassWrite, endpointClass("/2/team/members/set_admin_permissions_v2"))
+}
+
+// TestDoRequest_RetriesAfterRetryAfter verifies that a 429 is retried no
+// sooner than its Retry-After, and that the successful response reports the
+// client-side bucket as its rate limit.
+func TestDoRequest_RetriesAfterRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	var firstCall time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if calls.Add(1) == 1 {
+			firstCall = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error_summary": "too_many_requests/..", "error": {"reason": {".tag": "too_many_requests"}, "retry_after": 1}}`))
+			return
+		}
+		require.GreaterOrEqual(t, time.Since(firstCall), time.Second)
+		_, _ = w.Write([]byte(`{"members": [], "has_more": false}`))
+	}))
+	defer server.Close()
+
+	payload, rateLimit, err := newTestClient(t, server).ListUsers(context.Background(), 0)
+	require.NoError(t, err)
+	require.NotNil(t, payload)
+	require.EqualValues(t, 2, calls.Load())
+	require.EqualValues(t, DefaultRateLimits[EndpointClassRead].Burst, rateLimit.GetLimit())
+	require.Equal(t, v2.RateLimitDescription_STATUS_OK, rateLimit.GetStatus())
+}
+
+// TestDoRequest_SerializesWriteContention verifies that concurrent writes to
+// a namespace never overlap, including a write retrying after
+// too_many_write_operations.
+func TestDoRequest_SerializesWriteContention(t *testing.T) {
+	var calls, inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		if n > maxInFlight.Load() {
+			maxInFlight.Store(n)
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error_summary": "too_many_write_operations/..", "error": {"reason": {".tag": "too_many_write_operations"}}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, server)
+	c.retryBase = time.Millisecond
+	c.SetRateLimits(map[EndpointClass]RateLimit{EndpointClassWrite: {RequestsPerMinute: 6000, Burst: 10}})
+
+	var wg sync.WaitGroup
+	for _, member := range []string{"dbmid:1", "dbmid:2", "dbmid:3"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.AddUserToGroup(context.Background(), "g:1", member, "member")
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	require.EqualValues(t, 4, calls.Load())
+	require.EqualValues(t, 1, maxInFlight.Load())
+}
+
+// TestDoRequest_ThrottledWriteReleasesNamespaceLock verifies that a write
+// waiting to retry doesn't hold up other writes to its namespace.
+func TestDoRequest_ThrottledWriteReleasesNamespaceLock(t *testing.T) {
+	throttled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body["path"] == "/throttled" {
+			select {
+			case <-throttled:
+			default:
+				close(throttled)
+				w.WriteHeader(http.StatusTooManyRequests)
+				_, _ = w.Write([]byte(`{"error_summary": "too_many_write_operations/..", "error": {"reason": {".tag": "too_many_write_operations"}}}`))
+				return
+			}
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, server)
+	c.retryBase = time.Second
+	c.SetRateLimits(map[EndpointClass]RateLimit{EndpointClassWrite: {RequestsPerMinute: 6000, Burst: 10}})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.doRequest(context.Background(), c.url("/2/team/groups/members/add"), http.MethodPost, nil, map[string]string{"path": "/throttled"})
+		done <- err
+	}()
+	<-throttled
+
+	start := time.Now()
+	_, err := c.doRequest(context.Background(), c.url("/2/team/groups/members/add"), http.MethodPost, nil, map[string]string{"path": "/other"})
+	require.NoError(t, err)
+	require.Less(t, time.Since(start), 500*time.Millisecond, "the other write waited out the throttled write's backoff")
+
+	require.NoError(t, <-done)
+}
+
+// TestDoRequest_WritesToOtherNamespacesRunConcurrently verifies that the
+// namespace lock doesn't serialize writes to different namespaces.
+func TestDoRequest_WritesToOtherNamespacesRunConcurrently(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		if n > maxInFlight.Load() {
+			maxInFlight.Store(n)
+		}
+		time.Sleep(50 * time.Millisecond)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, server)
+	c.SetRateLimits(map[EndpointClass]RateLimit{EndpointClassWrite: {RequestsPerMinute: 6000, Burst: 10}})
+
+	var wg sync.WaitGroup
+	for _, member := range []string{"dbmid:1", "dbmid:2"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.doRequest(context.Background(), c.url("/2/files/create_folder_v2"), http.MethodPost, nil, map[string]string{"path": "/a"}, AsMember(member))
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	require.EqualValues(t, 2, maxInFlight.Load())
+}
+
+func TestDoRequest_GivesUpAfterMaxRetries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, server)
+	c.retryBase = time.Millisecond
+	c.maxRetries = 2
+
+	_, err := c.RemoveUserFromGroup(context.Background(), "g:1", "dbmid:1")
+	require.Error(t, err)
+	require.EqualValues(t, 3, calls.Load())
+}
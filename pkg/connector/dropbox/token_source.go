@@ -0,0 +1,90 @@
+package dropbox
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"golang.org/x/oauth2"
+)
+
+// tokenRefreshLeeway is how long before expiry a cached access token is
+// replaced. Dropbox short-lived tokens last about four hours; refreshing a few
+// minutes early keeps a request that starts just before expiry from failing.
+const tokenRefreshLeeway = 5 * time.Minute
+
+// tokenRefresher is implemented by token sources that can be told to discard
+// their cached token, e.g. after Dropbox rejects it as expired_access_token.
+type tokenRefresher interface {
+	ForceRefresh(ctx context.Context, rejected *oauth2.Token) error
+}
+
+// RefreshTokenSource is an oauth2.TokenSource that exchanges the client's
+// refresh token for short-lived access tokens (see
+// RequestAccessTokenUsingRefreshToken), caching each one until shortly before
+// it expires. It is safe for concurrent use; concurrent callers that find the
+// token stale wait for a single refresh rather than each starting their own.
+type RefreshTokenSource struct {
+	ctx    context.Context
+	client *Client
+
+	mu    sync.Mutex
+	token *oauth2.Token
+}
+
+var _ oauth2.TokenSource = (*RefreshTokenSource)(nil)
+var _ tokenRefresher = (*RefreshTokenSource)(nil)
+
+// NewRefreshTokenSource returns a token source that refreshes using client's
+// configured app key, app secret and refresh token. ctx is used for the
+// refresh requests, since oauth2.TokenSource.Token takes no context; it is
+// detached from ctx's cancellation so the source outlives the call that built it.
+func NewRefreshTokenSource(ctx context.Context, client *Client) *RefreshTokenSource {
+	return &RefreshTokenSource{
+		ctx:    context.WithoutCancel(ctx),
+		client: client,
+	}
+}
+
+// Token returns the cached access token, refreshing it first if it is missing
+// or within tokenRefreshLeeway of expiring.
+func (s *RefreshTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != nil && time.Until(s.token.Expiry) > tokenRefreshLeeway {
+		return s.token, nil
+	}
+
+	return s.refreshLocked(s.ctx)
+}
+
+// ForceRefresh replaces rejected, the access token Dropbox rejected, with a
+// new one. If the cached token was already replaced, e.g. by a concurrent
+// request rejected with the same token, it is kept, so concurrent rejections
+// cause a single refresh.
+func (s *RefreshTokenSource) ForceRefresh(ctx context.Context, rejected *oauth2.Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != nil && rejected != nil && s.token.AccessToken != rejected.AccessToken {
+		return nil
+	}
+
+	_, err := s.refreshLocked(ctx)
+	return err
+}
+
+func (s *RefreshTokenSource) refreshLocked(ctx context.Context) (*oauth2.Token, error) {
+	token, err := s.client.RequestAccessTokenUsingRefreshToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("dropbox-connector: error refreshing access token: %w", err)
+	}
+	s.token = token
+
+	ctxzap.Extract(ctx).Debug("dropbox-connector: refreshed access token")
+
+	return token, nil
+}
@@ -9,6 +9,19 @@ const (
 // API Documentation: https://www.dropbox.com/developers/documentation/http/documentation
 
 const (
+	// Team Endpoints
+
+	// GetTeamInfoURL returns information about the team; it fails for tokens
+	// that aren't team-scoped
+	// Docs: https://www.dropbox.com/developers/documentation/http/teams#team-get_info
+	// Required Scope: team_info.read.
+	GetTeamInfoURL = BaseURL + "/2/team/get_info"
+
+	// GetAuthenticatedAdminURL returns the admin who authorized the app
+	// Docs: https://www.dropbox.com/developers/documentation/http/teams#team-token-get_authenticated_admin
+	// Required Scope: team_info.read.
+	GetAuthenticatedAdminURL = BaseURL + "/2/team/token/get_authenticated_admin"
+
 	// User Management Endpoints
 	// Documentation: https://www.dropbox.com/developers/documentation/http/teams#team-members
 
@@ -22,6 +35,11 @@ const (
 	// Required Scope: members.read.
 	ListUsersContinueURL = BaseURL + "/2/team/members/list/continue_v2"
 
+	// GetMemberInfoURL fetches a single team member's profile
+	// Docs: https://www.dropbox.com/developers/documentation/http/teams#team-members-get_info
+	// Required Scope: members.read.
+	GetMemberInfoURL = BaseURL + "/2/team/members/get_info_v2"
+
 	// AddMemberURL provisions a new team member
 	// Docs: https://www.dropbox.com/developers/documentation/http/teams#team-members-add
 	// Required Scope: members.write
@@ -46,6 +64,30 @@ const (
 	// Permission: Team member management.
 	UnsuspendMemberURL = BaseURL + "/2/team/members/unsuspend"
 
+	// SendWelcomeEmailURL resends the invitation email to a pending member
+	// Docs: https://www.dropbox.com/developers/documentation/http/teams#team-members-send_welcome_email
+	// Required Scope: members.write
+	// Permission: Team member management.
+	SendWelcomeEmailURL = BaseURL + "/2/team/members/send_welcome_email"
+
+	// AddSecondaryEmailsURL adds secondary emails to a team member
+	// Docs: https://www.dropbox.com/developers/documentation/http/teams#team-members-secondary_emails-add
+	// Required Scope: members.write
+	// Permission: Team member management.
+	AddSecondaryEmailsURL = BaseURL + "/2/team/members/secondary_emails/add"
+
+	// DeleteSecondaryEmailsURL removes secondary emails from a team member
+	// Docs: https://www.dropbox.com/developers/documentation/http/teams#team-members-secondary_emails-delete
+	// Required Scope: members.write
+	// Permission: Team member management.
+	DeleteSecondaryEmailsURL = BaseURL + "/2/team/members/secondary_emails/delete"
+
+	// ResendSecondaryEmailVerificationURL resends verification for pending secondary emails
+	// Docs: https://www.dropbox.com/developers/documentation/http/teams#team-members-secondary_emails-resend_verification_emails
+	// Required Scope: members.write
+	// Permission: Team member management.
+	ResendSecondaryEmailVerificationURL = BaseURL + "/2/team/members/secondary_emails/resend_verification_emails"
+
 	// Role Management Endpoints
 	// Documentation: https://www.dropbox.com/developers/documentation/http/teams#team-members-set_admin_permissions
 
@@ -90,6 +132,16 @@ const (
 	// Permission: Team member management.
 	RemoveUserFromGroupURL = BaseURL + "/2/team/groups/members/remove"
 
+	// Device Endpoints
+	// Documentation: https://www.dropbox.com/developers/documentation/http/teams#team-devices-list_members_devices
+
+	// ListMembersDevicesURL lists the web sessions, desktop clients and mobile
+	// clients of every team member; later pages are requested with the cursor
+	// of the previous one.
+	// Docs: https://www.dropbox.com/developers/documentation/http/teams#team-devices-list_members_devices
+	// Required Scope: sessions.list.
+	ListMembersDevicesURL = BaseURL + "/2/team/devices/list_members_devices"
+
 	// Event Log Endpoints
 	// Documentation: https://www.dropbox.com/developers/documentation/http/teams#team_log-get_events
 
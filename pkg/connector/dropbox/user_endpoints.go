@@ -1,11 +1,12 @@
 package dropbox
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
@@ -37,90 +38,123 @@ func newUserActionRequest(teamMemberID string) userActionRequest {
 }
 
 func (c *Client) ListUsers(ctx context.Context, limit int) (*ListUsersPayload, *v2.RateLimitDescription, error) {
-	token, err := c.TokenSource.Token()
-	if err != nil {
-		return nil, nil, err
-	}
-
 	body := DefaultListUserBody()
+	body.Limit = c.pageSize(membersListing)
 	if limit != 0 {
 		body.Limit = limit
 	}
 	body.IncludeRemoved = true
 
-	reader := new(bytes.Buffer)
-	err = json.NewEncoder(reader).Encode(body)
+	var target ListUsersPayload
+	annos, err := c.doRequest(ctx, c.url("/2/team/members/list_v2"), http.MethodPost, &target, body)
 	if err != nil {
-		return nil, nil, err
+		return nil, getRateLimitFromAnnos(annos), fmt.Errorf("failed to list users: %w", err)
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url("/2/team/members/list_v2"), reader)
+
+	return &target, getRateLimitFromAnnos(annos), nil
+}
+
+func (c *Client) ListUsersContinue(ctx context.Context, cursor string) (*ListUsersPayload, *v2.RateLimitDescription, error) {
+	body := struct {
+		Cursor string `json:"cursor"`
+	}{Cursor: cursor}
+
+	var target ListUsersPayload
+	annos, err := c.doRequest(ctx, c.url("/2/team/members/list/continue_v2"), http.MethodPost, &target, body)
 	if err != nil {
-		return nil, nil, err
+		return nil, getRateLimitFromAnnos(annos), fmt.Errorf("failed to continue listing users: %w", err)
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token.AccessToken))
-	req.Header.Set("Content-Type", "application/json")
 
-	var target ListUsersPayload
-	var rateLimitData v2.RateLimitDescription
-	res, err := c.wrapper.Do(req,
-		uhttp.WithJSONResponse(&target),
-		uhttp.WithRatelimitData(&rateLimitData),
-	)
+	return &target, getRateLimitFromAnnos(annos), nil
+}
+
+// GetMemberInfo fetches a single team member's profile and roles using their
+// team_member_id. Returns a nil UserPayload (and no error) if Dropbox reports
+// that no member has that ID.
+// Based on API: POST /2/team/members/get_info_v2.
+func (c *Client) GetMemberInfo(ctx context.Context, teamMemberID string) (*UserPayload, *v2.RateLimitDescription, error) {
+	requestBody := GetMemberInfoBody{
+		Members: []TeamMemberIdTag{
+			{
+				Tag:          "team_member_id",
+				TeamMemberID: teamMemberID,
+			},
+		},
+	}
+
+	result := &GetMemberInfoPayload{}
+	annos, err := c.doRequest(ctx, c.url("/2/team/members/get_info_v2"), http.MethodPost, result, requestBody)
 	if err != nil {
-		logBody(ctx, res)
-		return nil, nil, err
+		return nil, getRateLimitFromAnnos(annos), fmt.Errorf("failed to get member info: %w", err)
 	}
 
-	defer res.Body.Close()
-	if res.StatusCode != http.StatusOK {
-		logBody(ctx, res)
-		return nil, nil, err
+	if len(result.MembersInfo) == 0 {
+		return nil, getRateLimitFromAnnos(annos), fmt.Errorf("received empty response from Dropbox API")
 	}
 
-	return &target, &rateLimitData, nil
+	item := result.MembersInfo[0]
+	if item.Tag != "member_info" {
+		return nil, getRateLimitFromAnnos(annos), nil
+	}
+	return &item.UserPayload, getRateLimitFromAnnos(annos), nil
 }
 
-func (c *Client) ListUsersContinue(ctx context.Context, cursor string) (*ListUsersPayload, *v2.RateLimitDescription, error) {
-	token, err := c.TokenSource.Token()
+// GetProfilePhoto downloads a team member's profile photo from the
+// profile_photo_url returned on their profile, returning the photo's content
+// type and body. The access token is only attached for https URLs on Dropbox
+// domains (or the configured API host), so a malformed profile can't make the
+// client send it elsewhere.
+func (c *Client) GetProfilePhoto(ctx context.Context, photoURL string) (string, io.ReadCloser, *v2.RateLimitDescription, error) {
+	parsedURL, err := url.Parse(photoURL)
 	if err != nil {
-		return nil, nil, err
+		return "", nil, nil, fmt.Errorf("failed to parse profile photo URL: %w", err)
+	}
+	if !c.isTrustedPhotoHost(parsedURL) {
+		return "", nil, nil, fmt.Errorf("refusing to fetch profile photo from untrusted host %q", parsedURL.Host)
 	}
 
-	body := struct {
-		Cursor string `json:"cursor"`
-	}{Cursor: cursor}
-
-	reader := new(bytes.Buffer)
-	err = json.NewEncoder(reader).Encode(body)
+	token, err := c.TokenSource.Token()
 	if err != nil {
-		return nil, nil, err
+		return "", nil, nil, fmt.Errorf("failed to get token: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url("/2/team/members/list/continue_v2"), reader)
+	request, err := c.wrapper.NewRequest(ctx, http.MethodGet, parsedURL, uhttp.WithBearerToken(token.AccessToken))
 	if err != nil {
-		return nil, nil, err
+		return "", nil, nil, fmt.Errorf("failed to create request: %w", err)
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token.AccessToken))
-	req.Header.Set("Content-Type", "application/json")
 
-	var target ListUsersPayload
-	var rateLimitData v2.RateLimitDescription
-	res, err := c.wrapper.Do(req,
-		uhttp.WithJSONResponse(&target),
-		uhttp.WithRatelimitData(&rateLimitData),
-	)
+	var ratelimitData v2.RateLimitDescription
+	response, err := c.wrapper.Do(request, uhttp.WithRatelimitData(&ratelimitData))
 	if err != nil {
-		logBody(ctx, res)
-		return nil, nil, err
+		return "", nil, &ratelimitData, fmt.Errorf("failed to get profile photo: %w", err)
 	}
 
-	defer res.Body.Close()
-	if res.StatusCode != http.StatusOK {
-		logBody(ctx, res)
-		return nil, nil, err
+	// uhttp has already buffered the body, so it stays readable after Do returns.
+	contentType := response.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
 	}
 
-	return &target, &rateLimitData, nil
+	return contentType, response.Body, &ratelimitData, nil
+}
+
+// isTrustedPhotoHost reports whether the bearer token may be sent to u.
+func (c *Client) isTrustedPhotoHost(u *url.URL) bool {
+	if base, err := url.Parse(c.baseURL); err == nil && u.Scheme == base.Scheme && u.Host == base.Host {
+		return true
+	}
+
+	if u.Scheme != "https" {
+		return false
+	}
+
+	host := u.Hostname()
+	for _, domain := range []string{"dropbox.com", "dropboxapi.com", "dropboxusercontent.com"} {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
 }
 
 // AddMember provisions a new team member.
@@ -132,7 +166,7 @@ func (c *Client) AddMember(ctx context.Context, email string) (*AddMemberRespons
 	result := &AddMemberResponse{}
 	annos, err := c.doRequest(ctx, c.url("/2/team/members/add_v2"), http.MethodPost, result, requestBody)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to add member: %w", err)
+		return nil, getRateLimitFromAnnos(annos), fmt.Errorf("failed to add member: %w", err)
 	}
 
 	return result, getRateLimitFromAnnos(annos), nil
@@ -151,7 +185,7 @@ func (c *Client) RemoveMember(ctx context.Context, teamMemberID string) (*Remove
 	result := &RemoveMemberResponse{}
 	annos, err := c.doRequest(ctx, c.url("/2/team/members/remove"), http.MethodPost, result, requestBody)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to remove member: %w", err)
+		return nil, getRateLimitFromAnnos(annos), fmt.Errorf("failed to remove member: %w", err)
 	}
 
 	if result.Tag == "" {
@@ -166,7 +200,7 @@ func (c *Client) RemoveMember(ctx context.Context, teamMemberID string) (*Remove
 func (c *Client) SuspendMember(ctx context.Context, teamMemberID string) (*v2.RateLimitDescription, error) {
 	annos, err := c.doRequest(ctx, c.url("/2/team/members/suspend"), http.MethodPost, nil, newUserActionRequest(teamMemberID))
 	if err != nil {
-		return nil, fmt.Errorf("failed to suspend member: %w", err)
+		return getRateLimitFromAnnos(annos), fmt.Errorf("failed to suspend member: %w", err)
 	}
 
 	return getRateLimitFromAnnos(annos), nil
@@ -177,7 +211,81 @@ func (c *Client) SuspendMember(ctx context.Context, teamMemberID string) (*v2.Ra
 func (c *Client) UnsuspendMember(ctx context.Context, teamMemberID string) (*v2.RateLimitDescription, error) {
 	annos, err := c.doRequest(ctx, c.url("/2/team/members/unsuspend"), http.MethodPost, nil, newUserActionRequest(teamMemberID))
 	if err != nil {
-		return nil, fmt.Errorf("failed to unsuspend member: %w", err)
+		return getRateLimitFromAnnos(annos), fmt.Errorf("failed to unsuspend member: %w", err)
+	}
+
+	return getRateLimitFromAnnos(annos), nil
+}
+
+// newUserSecondaryEmailsArg builds the per-user entry shared by the secondary_emails endpoints.
+func newUserSecondaryEmailsArg(teamMemberID string, emails []string) []UserSecondaryEmailsArg {
+	return []UserSecondaryEmailsArg{
+		{
+			User: TeamMemberIdTag{
+				Tag:          "team_member_id",
+				TeamMemberID: teamMemberID,
+			},
+			SecondaryEmails: emails,
+		},
+	}
+}
+
+// AddSecondaryEmails adds secondary emails to a team member. Dropbox sends a
+// verification email to each new address.
+// Based on API: POST /2/team/members/secondary_emails/add.
+func (c *Client) AddSecondaryEmails(ctx context.Context, teamMemberID string, emails []string) (*SecondaryEmailsPayload, *v2.RateLimitDescription, error) {
+	requestBody := AddSecondaryEmailsBody{NewSecondaryEmails: newUserSecondaryEmailsArg(teamMemberID, emails)}
+
+	result := &SecondaryEmailsPayload{}
+	annos, err := c.doRequest(ctx, c.url("/2/team/members/secondary_emails/add"), http.MethodPost, result, requestBody)
+	if err != nil {
+		return nil, getRateLimitFromAnnos(annos), fmt.Errorf("failed to add secondary emails: %w", err)
+	}
+
+	return result, getRateLimitFromAnnos(annos), nil
+}
+
+// DeleteSecondaryEmails removes secondary emails from a team member.
+// Based on API: POST /2/team/members/secondary_emails/delete.
+func (c *Client) DeleteSecondaryEmails(ctx context.Context, teamMemberID string, emails []string) (*SecondaryEmailsPayload, *v2.RateLimitDescription, error) {
+	requestBody := DeleteSecondaryEmailsBody{EmailsToDelete: newUserSecondaryEmailsArg(teamMemberID, emails)}
+
+	result := &SecondaryEmailsPayload{}
+	annos, err := c.doRequest(ctx, c.url("/2/team/members/secondary_emails/delete"), http.MethodPost, result, requestBody)
+	if err != nil {
+		return nil, getRateLimitFromAnnos(annos), fmt.Errorf("failed to delete secondary emails: %w", err)
+	}
+
+	return result, getRateLimitFromAnnos(annos), nil
+}
+
+// ResendSecondaryEmailVerification resends verification emails for a team
+// member's pending (unverified) secondary emails.
+// Based on API: POST /2/team/members/secondary_emails/resend_verification_emails.
+func (c *Client) ResendSecondaryEmailVerification(ctx context.Context, teamMemberID string, emails []string) (*SecondaryEmailsPayload, *v2.RateLimitDescription, error) {
+	requestBody := ResendVerificationEmailsBody{EmailsToResend: newUserSecondaryEmailsArg(teamMemberID, emails)}
+
+	result := &SecondaryEmailsPayload{}
+	annos, err := c.doRequest(ctx, c.url("/2/team/members/secondary_emails/resend_verification_emails"), http.MethodPost, result, requestBody)
+	if err != nil {
+		return nil, getRateLimitFromAnnos(annos), fmt.Errorf("failed to resend secondary email verification: %w", err)
+	}
+
+	return result, getRateLimitFromAnnos(annos), nil
+}
+
+// SendWelcomeEmail resends the invitation email to a team member whose
+// invitation is still pending.
+// Based on API: POST /2/team/members/send_welcome_email.
+func (c *Client) SendWelcomeEmail(ctx context.Context, teamMemberID string) (*v2.RateLimitDescription, error) {
+	requestBody := SendWelcomeEmailBody{
+		Tag:          "team_member_id",
+		TeamMemberID: teamMemberID,
+	}
+
+	annos, err := c.doRequest(ctx, c.url("/2/team/members/send_welcome_email"), http.MethodPost, nil, requestBody)
+	if err != nil {
+		return getRateLimitFromAnnos(annos), fmt.Errorf("failed to send welcome email: %w", err)
 	}
 
 	return getRateLimitFromAnnos(annos), nil
@@ -0,0 +1,70 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-dropbox/pkg/connector/dropbox"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const fileOperationEventsJSON = `{"events": [
+	{"timestamp": "2024-01-02T09:00:00Z", "event_category": {".tag": "file_operations"}, "event_type": {".tag": "file_edit"},
+	 "actor": {".tag": "user", "user": {".tag": "team_member", "team_member_id": "dbmid:1", "email": "alice@example.com", "display_name": "Alice"}},
+	 "assets": [{".tag": "file", "display_name": "plan.txt", "path": {"contextual": "/Projects/Launch/docs/plan.txt",
+		"namespace_relative": {"ns_id": "1001", "relative_path": "/docs/plan.txt", "is_shared_namespace": true}}}]},
+	{"timestamp": "2024-01-02T15:00:00Z", "event_category": {".tag": "file_operations"}, "event_type": {".tag": "file_preview"},
+	 "actor": {".tag": "user", "user": {".tag": "team_member", "team_member_id": "dbmid:1", "email": "alice@example.com", "display_name": "Alice"}},
+	 "assets": [{".tag": "file", "display_name": "notes.txt", "path": {"contextual": "/Projects/Launch/notes.txt",
+		"namespace_relative": {"ns_id": "1001", "relative_path": "/notes.txt", "is_shared_namespace": true}}}]},
+	{"timestamp": "2024-01-03T08:00:00Z", "event_category": {".tag": "file_operations"}, "event_type": {".tag": "file_edit"},
+	 "actor": {".tag": "user", "user": {".tag": "team_member", "team_member_id": "dbmid:1", "email": "alice@example.com", "display_name": "Alice"}},
+	 "assets": [{".tag": "file", "display_name": "plan.txt", "path": {"contextual": "/Projects/Launch/docs/plan.txt",
+		"namespace_relative": {"ns_id": "1001", "relative_path": "/docs/plan.txt", "is_shared_namespace": true}}}]},
+	{"timestamp": "2024-01-03T09:00:00Z", "event_category": {".tag": "file_operations"}, "event_type": {".tag": "file_edit"},
+	 "actor": {".tag": "user", "user": {".tag": "team_member", "team_member_id": "dbmid:1", "email": "alice@example.com", "display_name": "Alice"}},
+	 "assets": [{".tag": "file", "display_name": "todo.txt", "path": {"contextual": "/todo.txt",
+		"namespace_relative": {"ns_id": "2001", "relative_path": "/todo.txt", "is_shared_namespace": false}}}]}
+], "cursor": "files-cursor", "has_more": false}`
+
+// TestFolderActivityFeed_ListEvents_AggregatesPerMemberFolderAndDay verifies
+// that activity in a shared namespace becomes one usage event per member,
+// folder and day, and that activity in members' own folders is ignored.
+func TestFolderActivityFeed_ListEvents_AggregatesPerMemberFolderAndDay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body dropbox.GetTeamEventsBody
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Equal(t, "file_operations", body.Category.Tag)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(fileOperationEventsJSON))
+	}))
+	defer server.Close()
+
+	client, err := dropbox.NewClient(context.Background(), dropbox.Config{BaseURL: server.URL})
+	require.NoError(t, err)
+	client.TokenSource = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "test-token"})
+	feed := newFolderActivityFeed(client)
+	startAt := timestamppb.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	events, state, _, err := feed.ListEvents(context.Background(), startAt, nil)
+	require.NoError(t, err)
+	require.True(t, state.HasMore, "the sharing category is still to be read")
+	require.Len(t, events, 2)
+
+	require.Equal(t, "folder-activity-dbmid:1-1001-2024-01-02", events[0].GetId())
+	require.Equal(t, time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC), events[0].GetOccurredAt().AsTime())
+	usage := events[0].GetUsageEvent()
+	require.Equal(t, folderResourceType.Id, usage.GetTargetResource().GetId().GetResourceType())
+	require.Equal(t, "1001", usage.GetTargetResource().GetId().GetResource())
+	require.Equal(t, "Launch", usage.GetTargetResource().GetDisplayName())
+	require.Equal(t, "dbmid:1", usage.GetActorResource().GetId().GetResource())
+
+	require.Equal(t, "folder-activity-dbmid:1-1001-2024-01-03", events[1].GetId())
+}
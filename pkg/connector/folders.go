@@ -0,0 +1,105 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conductorone/baton-dropbox/pkg/connector/dropbox"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	resourceSdk "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+// folderNamespaceTypes are the namespace types synced as folders. Members'
+// home folders and app folders are private to one member, so folder activity
+// never targets them.
+var folderNamespaceTypes = map[string]bool{
+	"team_folder":   true,
+	"shared_folder": true,
+}
+
+// folderBuilder syncs the team folders and shared folders of the team from
+// team/namespaces/list, keyed by namespace ID, so folderActivityFeed's usage
+// events and resourceChangeFeed's folder changes have a resource to target.
+// Gated behind the sync-folder-activity and sync-resource-change-events
+// config flags; either one enables it.
+type folderBuilder struct {
+	client *dropbox.Client
+}
+
+func newFolderBuilder(client *dropbox.Client) *folderBuilder {
+	return &folderBuilder{client: client}
+}
+
+func folderResource(namespace dropbox.NamespaceMetadata, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	return resourceSdk.NewResource(
+		namespace.Name,
+		folderResourceType,
+		namespace.NamespaceID,
+		resourceSdk.WithResourceProfile(map[string]interface{}{
+			"namespace_id":   namespace.NamespaceID,
+			"namespace_type": namespace.NamespaceType.Tag,
+			"name":           namespace.Name,
+		}),
+		resourceSdk.WithParentResourceID(parentResourceID),
+	)
+}
+
+func (b *folderBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return folderResourceType
+}
+
+func (b *folderBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, attr resourceSdk.SyncOpAttrs) ([]*v2.Resource, *resourceSdk.SyncOpResults, error) {
+	namespaces, nextToken, rateLimitData, err := nextListPage(ctx, newListingSession(attr.Session, "folders"), attr.PageToken.Token,
+		func(namespace dropbox.NamespaceMetadata) string { return namespace.NamespaceID },
+		func(ctx context.Context) (listPage[dropbox.NamespaceMetadata], *v2.RateLimitDescription, error) {
+			payload, rateLimitData, err := b.client.ListNamespaces(ctx)
+			if err != nil {
+				return listPage[dropbox.NamespaceMetadata]{}, rateLimitData, err
+			}
+			return listPage[dropbox.NamespaceMetadata]{payload.Namespaces, payload.Cursor, payload.HasMore}, rateLimitData, nil
+		},
+		func(ctx context.Context, cursor string) (listPage[dropbox.NamespaceMetadata], *v2.RateLimitDescription, error) {
+			payload, rateLimitData, err := b.client.ListNamespacesContinue(ctx, cursor)
+			if err != nil {
+				return listPage[dropbox.NamespaceMetadata]{}, rateLimitData, err
+			}
+			return listPage[dropbox.NamespaceMetadata]{payload.Namespaces, payload.Cursor, payload.HasMore}, rateLimitData, nil
+		},
+	)
+
+	var outAnnotations annotations.Annotations
+	outAnnotations.WithRateLimiting(rateLimitData)
+	if err != nil {
+		return nil, &resourceSdk.SyncOpResults{
+			Annotations: outAnnotations,
+		}, fmt.Errorf("dropbox-connector: failed to list namespaces: %w", err)
+	}
+
+	var outResources []*v2.Resource
+	for _, namespace := range namespaces {
+		if !folderNamespaceTypes[namespace.NamespaceType.Tag] {
+			continue
+		}
+		resource, err := folderResource(namespace, parentResourceID)
+		if err != nil {
+			return nil, &resourceSdk.SyncOpResults{
+				Annotations: outAnnotations,
+			}, err
+		}
+		outResources = append(outResources, resource)
+	}
+
+	return outResources, &resourceSdk.SyncOpResults{
+		NextPageToken: nextToken,
+		Annotations:   outAnnotations,
+	}, nil
+}
+
+func (b *folderBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ resourceSdk.SyncOpAttrs) ([]*v2.Entitlement, *resourceSdk.SyncOpResults, error) {
+	return nil, nil, nil
+}
+
+func (b *folderBuilder) Grants(_ context.Context, _ *v2.Resource, _ resourceSdk.SyncOpAttrs) ([]*v2.Grant, *resourceSdk.SyncOpResults, error) {
+	return nil, nil, nil
+}
@@ -0,0 +1,53 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	resourceSdk "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFolderBuilder_List_SyncsTeamAndSharedFolders verifies that team folders
+// and shared folders are synced keyed by namespace ID, across pages, and that
+// members' home folders and app folders are not.
+func TestFolderBuilder_List_SyncsTeamAndSharedFolders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/2/team/namespaces/list":
+			_, _ = w.Write([]byte(`{"namespaces": [
+				{"name": "Projects", "namespace_id": "1001", "namespace_type": {".tag": "team_folder"}},
+				{"name": "Alice", "namespace_id": "2001", "namespace_type": {".tag": "team_member_folder"}, "team_member_id": "dbmid:1"}
+			], "cursor": "ns-cursor", "has_more": true}`))
+		case "/2/team/namespaces/list/continue":
+			_, _ = w.Write([]byte(`{"namespaces": [
+				{"name": "Launch", "namespace_id": "1002", "namespace_type": {".tag": "shared_folder"}, "team_member_id": "dbmid:1"},
+				{"name": "Apps", "namespace_id": "3001", "namespace_type": {".tag": "app_folder"}, "team_member_id": "dbmid:1"}
+			], "cursor": "ns-cursor-2", "has_more": false}`))
+		default:
+			t.Fatalf("unexpected path %q", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	builder := newFolderBuilder(newTestConnector(t, server).client)
+
+	var ids []string
+	token := ""
+	for {
+		resources, results, err := builder.List(context.Background(), nil, resourceSdk.SyncOpAttrs{PageToken: pagination.Token{Token: token}})
+		require.NoError(t, err)
+		for _, resource := range resources {
+			require.Equal(t, folderResourceType.Id, resource.GetId().GetResourceType())
+			ids = append(ids, resource.GetId().GetResource())
+		}
+		if token = results.NextPageToken; token == "" {
+			break
+		}
+	}
+	require.Equal(t, []string{"1001", "1002"}, ids)
+}
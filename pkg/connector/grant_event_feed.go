@@ -0,0 +1,297 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/conductorone/baton-dropbox/pkg/connector/dropbox"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const grantEventFeedID = "dropbox_grant_event_feed"
+
+// grantEventCategories are the team_log categories read by grantEventFeed, in
+// the order they are read.
+var grantEventCategories = []string{"groups", "members"}
+
+const (
+	groupAddMemberEventType        = "group_add_member"
+	groupRemoveMemberEventType     = "group_remove_member"
+	groupChangeMemberRoleEventType = "group_change_member_role"
+	memberChangeAdminRoleEventType = "member_change_admin_role"
+)
+
+// grantEventFeed emits CreateGrant and CreateRevoke events for group
+// memberships and admin roles changed in Dropbox, derived from the team event
+// audit log, so access changed in the Dropbox console shows up in C1 between
+// full syncs. Gated behind the sync-grant-events config flag since it
+// requires the events.read scope.
+type grantEventFeed struct {
+	client *dropbox.Client
+}
+
+func newGrantEventFeed(client *dropbox.Client) *grantEventFeed {
+	return &grantEventFeed{client: client}
+}
+
+func (f *grantEventFeed) EventFeedMetadata(_ context.Context) *v2.EventFeedMetadata {
+	return &v2.EventFeedMetadata{
+		Id: grantEventFeedID,
+		SupportedEventTypes: []v2.EventType{
+			v2.EventType_EVENT_TYPE_CREATE_GRANT,
+			v2.EventType_EVENT_TYPE_CREATE_REVOKE,
+		},
+	}
+}
+
+// accessChange is one grant or revoke derived from a team event.
+type accessChange struct {
+	entitlement *v2.Entitlement
+	principal   *v2.Resource
+	revoke      bool
+}
+
+func (f *grantEventFeed) ListEvents(
+	ctx context.Context,
+	startAt *timestamppb.Timestamp,
+	pToken *pagination.StreamToken,
+) ([]*v2.Event, *pagination.StreamState, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	teamEvents, state, rateLimitData, err := nextCategoryEventPage(ctx, f.client, grantEventCategories, startAt, pToken)
+
+	var outAnnotations annotations.Annotations
+	outAnnotations.WithRateLimiting(rateLimitData)
+
+	if err != nil {
+		return nil, nil, outAnnotations, err
+	}
+
+	// Admin role events name roles by AdminRole tag rather than role ID; the
+	// roles are only looked up when a page has such an event.
+	var roles map[string]dropbox.Role
+	unmatchedRoles := map[string]bool{}
+
+	events := make([]*v2.Event, 0, len(teamEvents))
+	for _, e := range teamEvents {
+		var changes []accessChange
+		switch e.EventType.Tag {
+		case groupAddMemberEventType, groupRemoveMemberEventType, groupChangeMemberRoleEventType:
+			changes, err = groupAccessChanges(e.TeamEvent)
+		case memberChangeAdminRoleEventType:
+			if roles == nil {
+				available, rateLimitData, rolesErr := f.client.GetAvailableRoles(ctx)
+				if rolesErr != nil {
+					outAnnotations.WithRateLimiting(rateLimitData)
+					return nil, nil, outAnnotations, fmt.Errorf("dropbox-connector: failed to list roles: %w", rolesErr)
+				}
+				roles = rolesByAdminRole(available)
+			}
+			var unmatched []string
+			changes, unmatched, err = roleAccessChanges(e.TeamEvent, roles)
+			for _, tag := range unmatched {
+				if !unmatchedRoles[tag] {
+					unmatchedRoles[tag] = true
+					l.Warn("dropbox-connector: admin role change names a role with no matching role resource, skipping it",
+						zap.String("admin_role", tag),
+						zap.String("timestamp", e.Timestamp),
+					)
+				}
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			l.Debug("dropbox-connector: skipping unparseable access change event",
+				zap.String("event_type", e.EventType.Tag),
+				zap.String("timestamp", e.Timestamp),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		for _, change := range changes {
+			event := &v2.Event{
+				Id:         fmt.Sprintf("%s-%s", e.ID(), change.entitlement.GetId()),
+				OccurredAt: timestamppb.New(e.OccurredAt),
+			}
+			if change.revoke {
+				event.Event = &v2.Event_CreateRevokeEvent{
+					CreateRevokeEvent: &v2.CreateRevokeEvent{
+						Entitlement: change.entitlement,
+						Principal:   change.principal,
+					},
+				}
+			} else {
+				event.Event = &v2.Event_CreateGrantEvent{
+					CreateGrantEvent: &v2.CreateGrantEvent{
+						Entitlement: change.entitlement,
+						Principal:   change.principal,
+					},
+				}
+			}
+			events = append(events, event)
+		}
+	}
+
+	return events, state, outAnnotations, nil
+}
+
+// groupAccessChanges returns the group member and owner grants changed by a
+// group_add_member, group_remove_member or group_change_member_role event.
+// Removal events don't say whether the member was an owner, so both grants
+// are revoked.
+func groupAccessChanges(e dropbox.TeamEvent) ([]accessChange, error) {
+	group := e.Group()
+	if group == nil {
+		return nil, fmt.Errorf("event has no group participant")
+	}
+	principal := eventMember(e)
+	if principal == nil {
+		return nil, fmt.Errorf("event has no team member")
+	}
+
+	groupResource := &v2.Resource{
+		Id:          &v2.ResourceId{ResourceType: groupResourceType.Id, Resource: group.GroupID},
+		DisplayName: group.DisplayName,
+	}
+	member := accessEntitlement(groupResource, groupMembership)
+	owner := accessEntitlement(groupResource, groupOwner)
+
+	if e.EventType.Tag == groupRemoveMemberEventType {
+		return []accessChange{
+			{entitlement: member, principal: principal, revoke: true},
+			{entitlement: owner, principal: principal, revoke: true},
+		}, nil
+	}
+
+	var details dropbox.GroupMemberDetails
+	if err := e.DecodeDetails(&details); err != nil {
+		return nil, err
+	}
+	granted, revoked := member, owner
+	if details.IsGroupOwner {
+		granted, revoked = owner, member
+	}
+
+	changes := []accessChange{{entitlement: granted, principal: principal}}
+	// Group listings grant owners only the owner entitlement, so a role change
+	// moves the member from one entitlement to the other.
+	if e.EventType.Tag == groupChangeMemberRoleEventType {
+		changes = append(changes, accessChange{entitlement: revoked, principal: principal, revoke: true})
+	}
+	return changes, nil
+}
+
+// roleAccessChanges returns the role member grants changed by a
+// member_change_admin_role event, and the AdminRole tags it names that match
+// no role in roles. member_only means no admin role and is never unmatched.
+func roleAccessChanges(e dropbox.TeamEvent, roles map[string]dropbox.Role) ([]accessChange, []string, error) {
+	principal := eventMember(e)
+	if principal == nil {
+		return nil, nil, fmt.Errorf("event has no team member")
+	}
+
+	var details dropbox.AdminRoleDetails
+	if err := e.DecodeDetails(&details); err != nil {
+		return nil, nil, err
+	}
+
+	var changes []accessChange
+	var unmatched []string
+	for _, value := range []struct {
+		tag    *dropbox.Tag
+		revoke bool
+	}{{details.PreviousValue, true}, {details.NewValue, false}} {
+		if value.tag == nil || value.tag.Tag == memberOnlyAdminRole {
+			continue
+		}
+		role, ok := roles[value.tag.Tag]
+		if !ok {
+			unmatched = append(unmatched, value.tag.Tag)
+			continue
+		}
+		changes = append(changes, accessChange{entitlement: roleEntitlement(role), principal: principal, revoke: value.revoke})
+	}
+	return changes, unmatched, nil
+}
+
+// memberOnlyAdminRole is the AdminRole tag of members without an admin role.
+const memberOnlyAdminRole = "member_only"
+
+// adminRoleNames maps the AdminRole tags team events use to the names
+// get_available_team_member_roles returns for those roles. Role IDs are
+// opaque, so roles are matched by name.
+var adminRoleNames = map[string]string{
+	"team_admin":            "Team admin",
+	"user_management_admin": "User management admin",
+	"support_admin":         "Support admin",
+	"billing_admin":         "Billing admin",
+	"content_admin":         "Content admin",
+	"compliance_admin":      "Compliance admin",
+	"reporting_admin":       "Reporting admin",
+	"security_admin":        "Security admin",
+}
+
+// rolesByAdminRole keys roles by the AdminRole tag team events use for them,
+// per adminRoleNames. Roles with no tag in adminRoleNames are left out.
+func rolesByAdminRole(roles []dropbox.Role) map[string]dropbox.Role {
+	byTag := make(map[string]dropbox.Role, len(roles))
+	for tag, name := range adminRoleNames {
+		for _, role := range roles {
+			if strings.EqualFold(strings.TrimSpace(role.Name), name) {
+				byTag[tag] = role
+				break
+			}
+		}
+	}
+	return byTag
+}
+
+// eventMember returns the team member an event affected, from its user
+// participant or, failing that, its context.
+func eventMember(e dropbox.TeamEvent) *v2.Resource {
+	member := e.Context.TeamMember()
+	for _, participant := range e.Participants {
+		if participant.Tag == "user" && participant.User != nil && participant.User.TeamMemberID != "" {
+			member = participant.User
+			break
+		}
+	}
+	if member == nil {
+		return nil
+	}
+
+	displayName := member.DisplayName
+	if displayName == "" {
+		displayName = member.Email
+	}
+	return &v2.Resource{
+		Id:          &v2.ResourceId{ResourceType: userResourceType.Id, Resource: member.TeamMemberID},
+		DisplayName: displayName,
+	}
+}
+
+func roleEntitlement(role dropbox.Role) *v2.Entitlement {
+	return accessEntitlement(&v2.Resource{
+		Id:          &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: role.RoleID},
+		DisplayName: role.Name,
+	}, roleMembership)
+}
+
+// accessEntitlement returns the entitlement with slug on resource, with the
+// ID the resource's builder gives it.
+func accessEntitlement(resource *v2.Resource, slug string) *v2.Entitlement {
+	return entitlement.NewAssignmentEntitlement(
+		resource,
+		slug,
+		entitlement.WithGrantableTo(userResourceType),
+	)
+}
@@ -0,0 +1,150 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-dropbox/pkg/connector/dropbox"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const groupAccessEventsJSON = `{"events": [
+	{"timestamp": "2024-01-02T10:00:00Z", "event_category": {".tag": "groups"}, "event_type": {".tag": "group_add_member"},
+	 "actor": {".tag": "admin", "admin": {".tag": "team_member", "team_member_id": "dbmid:admin"}},
+	 "context": {".tag": "team"},
+	 "participants": [
+		{".tag": "user", "user": {".tag": "team_member", "team_member_id": "dbmid:1", "email": "alice@example.com"}},
+		{".tag": "group", "group_id": "g:1", "display_name": "Engineering"}],
+	 "details": {".tag": "group_add_member_details", "is_group_owner": false}},
+	{"timestamp": "2024-01-02T11:00:00Z", "event_category": {".tag": "groups"}, "event_type": {".tag": "group_change_member_role"},
+	 "actor": {".tag": "admin", "admin": {".tag": "team_member", "team_member_id": "dbmid:admin"}},
+	 "context": {".tag": "team_member", "team_member_id": "dbmid:1"},
+	 "participants": [{".tag": "group", "group_id": "g:1", "display_name": "Engineering"}],
+	 "details": {".tag": "group_change_member_role_details", "is_group_owner": true}},
+	{"timestamp": "2024-01-02T12:00:00Z", "event_category": {".tag": "groups"}, "event_type": {".tag": "group_rename"},
+	 "actor": {".tag": "admin", "admin": {".tag": "team_member", "team_member_id": "dbmid:admin"}},
+	 "context": {".tag": "team"},
+	 "participants": [{".tag": "group", "group_id": "g:1", "display_name": "Engineering"}],
+	 "details": {".tag": "group_rename_details", "previous_value": "Eng", "new_value": "Engineering"}}
+], "cursor": "groups-cursor", "has_more": false}`
+
+const roleAccessEventsJSON = `{"events": [
+	{"timestamp": "2024-01-03T10:00:00Z", "event_category": {".tag": "members"}, "event_type": {".tag": "member_change_admin_role"},
+	 "actor": {".tag": "admin", "admin": {".tag": "team_member", "team_member_id": "dbmid:admin"}},
+	 "context": {".tag": "team_member", "team_member_id": "dbmid:2", "email": "bob@example.com"},
+	 "details": {".tag": "member_change_admin_role_details",
+		"previous_value": {".tag": "support_admin"}, "new_value": {".tag": "user_management_admin"}}},
+	{"timestamp": "2024-01-03T11:00:00Z", "event_category": {".tag": "members"}, "event_type": {".tag": "member_change_email"},
+	 "actor": {".tag": "admin", "admin": {".tag": "team_member", "team_member_id": "dbmid:admin"}},
+	 "context": {".tag": "team_member", "team_member_id": "dbmid:2"},
+	 "details": {".tag": "member_change_email_details", "new_value": "bob@example.org"}}
+], "cursor": "members-cursor", "has_more": false}`
+
+const availableRolesJSON = `{"roles": [
+	{"role_id": "pid_dbtmr:support", "name": "Support admin", "description": "Support admin"},
+	{"role_id": "pid_dbtmr:users", "name": "User management admin", "description": "User management admin"}
+]}`
+
+// TestGrantEventFeed_ListEvents verifies that group membership and admin role
+// changes become grants and revokes on the entitlements the builders sync.
+func TestGrantEventFeed_ListEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/2/team/members/get_available_team_member_roles" {
+			_, _ = w.Write([]byte(availableRolesJSON))
+			return
+		}
+
+		require.Equal(t, "/2/team_log/get_events", r.URL.Path)
+		var body dropbox.GetTeamEventsBody
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		switch body.Category.Tag {
+		case "groups":
+			_, _ = w.Write([]byte(groupAccessEventsJSON))
+		case "members":
+			_, _ = w.Write([]byte(roleAccessEventsJSON))
+		default:
+			t.Fatalf("unexpected category %q", body.Category.Tag)
+		}
+	}))
+	defer server.Close()
+
+	client, err := dropbox.NewClient(context.Background(), dropbox.Config{BaseURL: server.URL})
+	require.NoError(t, err)
+	client.TokenSource = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "test-token"})
+	feed := newGrantEventFeed(client)
+	startAt := timestamppb.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	events, state, _, err := feed.ListEvents(context.Background(), startAt, nil)
+	require.NoError(t, err)
+	require.True(t, state.HasMore)
+	require.Equal(t, []string{
+		"grant group:g:1:member dbmid:1",
+		"grant group:g:1:owner dbmid:1",
+		"revoke group:g:1:member dbmid:1",
+	}, describeAccessEvents(events))
+
+	events, state, _, err = feed.ListEvents(context.Background(), startAt, &pagination.StreamToken{Cursor: state.Cursor})
+	require.NoError(t, err)
+	require.False(t, state.HasMore)
+	require.Equal(t, []string{
+		"revoke role:pid_dbtmr:support:member dbmid:2",
+		"grant role:pid_dbtmr:users:member dbmid:2",
+	}, describeAccessEvents(events))
+}
+
+func describeAccessEvents(events []*v2.Event) []string {
+	described := make([]string, 0, len(events))
+	for _, e := range events {
+		if grant := e.GetCreateGrantEvent(); grant != nil {
+			described = append(described, "grant "+grant.GetEntitlement().GetId()+" "+grant.GetPrincipal().GetId().GetResource())
+		}
+		if revoke := e.GetCreateRevokeEvent(); revoke != nil {
+			described = append(described, "revoke "+revoke.GetEntitlement().GetId()+" "+revoke.GetPrincipal().GetId().GetResource())
+		}
+	}
+	return described
+}
+
+// TestRoleAccessChanges_ReportsUnmatchedAdminRoles verifies that roles are
+// matched to AdminRole tags by name, and that tags with no matching role are
+// reported rather than guessed.
+func TestRoleAccessChanges_ReportsUnmatchedAdminRoles(t *testing.T) {
+	roles := rolesByAdminRole([]dropbox.Role{
+		{RoleID: "pid_dbtmr:team", Name: "Team Admin"},
+		{RoleID: "pid_dbtmr:custom", Name: "Custom reviewer"},
+	})
+	require.Equal(t, map[string]dropbox.Role{
+		"team_admin": {RoleID: "pid_dbtmr:team", Name: "Team Admin"},
+	}, roles)
+
+	var e dropbox.TeamEvent
+	require.NoError(t, json.Unmarshal([]byte(`{"timestamp": "2024-01-03T10:00:00Z", "event_type": {".tag": "member_change_admin_role"},
+		"context": {".tag": "team_member", "team_member_id": "dbmid:2"},
+		"details": {".tag": "member_change_admin_role_details", "previous_value": {".tag": "billing_admin"}, "new_value": {".tag": "team_admin"}}}`), &e))
+
+	changes, unmatched, err := roleAccessChanges(e, roles)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	require.Equal(t, "role:pid_dbtmr:team:member", changes[0].entitlement.GetId())
+	require.False(t, changes[0].revoke)
+	require.Equal(t, []string{"billing_admin"}, unmatched)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"timestamp": "2024-01-03T10:00:00Z", "event_type": {".tag": "member_change_admin_role"},
+		"context": {".tag": "team_member", "team_member_id": "dbmid:2"},
+		"details": {".tag": "member_change_admin_role_details", "previous_value": {".tag": "team_admin"}, "new_value": {".tag": "member_only"}}}`), &e))
+
+	changes, unmatched, err = roleAccessChanges(e, roles)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	require.True(t, changes[0].revoke)
+	require.Empty(t, unmatched)
+}
@@ -22,7 +22,6 @@ type groupBuilder struct {
 
 const groupMembership = "member"
 const groupOwner = "owner"
-const limit = 100
 
 func groupResource(group dropbox.Group, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
 	return resourceSdk.NewGroupResource(
@@ -50,15 +49,23 @@ func (o *groupBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId
 	logger.Debug("Starting Groups List", zap.String("token", token))
 	outResources := []*v2.Resource{}
 
-	var payload *dropbox.ListGroupsPayload
-	var rateLimitData *v2.RateLimitDescription
-	var err error
-
-	if token == "" {
-		payload, rateLimitData, err = o.ListGroups(ctx, limit)
-	} else {
-		payload, rateLimitData, err = o.ListGroupsContinue(ctx, token)
-	}
+	groups, nextToken, rateLimitData, err := nextListPage(ctx, newListingSession(attr.Session, "groups"), token,
+		func(group dropbox.Group) string { return group.GroupID },
+		func(ctx context.Context) (listPage[dropbox.Group], *v2.RateLimitDescription, error) {
+			payload, rateLimitData, err := o.ListGroups(ctx, 0)
+			if err != nil {
+				return listPage[dropbox.Group]{}, rateLimitData, err
+			}
+			return listPage[dropbox.Group]{payload.Groups, payload.Cursor, payload.HasMore}, rateLimitData, nil
+		},
+		func(ctx context.Context, cursor string) (listPage[dropbox.Group], *v2.RateLimitDescription, error) {
+			payload, rateLimitData, err := o.ListGroupsContinue(ctx, cursor)
+			if err != nil {
+				return listPage[dropbox.Group]{}, rateLimitData, err
+			}
+			return listPage[dropbox.Group]{payload.Groups, payload.Cursor, payload.HasMore}, rateLimitData, nil
+		},
+	)
 
 	var outAnnotations annotations.Annotations
 	outAnnotations.WithRateLimiting(rateLimitData)
@@ -68,7 +75,7 @@ func (o *groupBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId
 		}, fmt.Errorf("error listing groups: %w", err)
 	}
 
-	for _, group := range payload.Groups {
+	for _, group := range groups {
 		groupResource, err := groupResource(group, parentResourceID)
 		if err != nil {
 			return nil, &resourceSdk.SyncOpResults{
@@ -78,13 +85,8 @@ func (o *groupBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId
 		outResources = append(outResources, groupResource)
 	}
 
-	var cursor string
-	if payload.HasMore {
-		cursor = payload.Cursor
-	}
-
 	return outResources, &resourceSdk.SyncOpResults{
-		NextPageToken: cursor,
+		NextPageToken: nextToken,
 		Annotations:   outAnnotations,
 	}, nil
 }
@@ -109,28 +111,46 @@ func (o *groupBuilder) Entitlements(_ context.Context, resource *v2.Resource, _
 	}, nil, nil
 }
 
+// Grants lists the group's members. A group deleted mid-sync ends its grants
+// instead of failing the sync.
 func (o *groupBuilder) Grants(ctx context.Context, resource *v2.Resource, attr resourceSdk.SyncOpAttrs) ([]*v2.Grant, *resourceSdk.SyncOpResults, error) {
 	var outGrants []*v2.Grant
-	var payload *dropbox.ListGroupMembersPayload
-	var err error
-	var rateLimitData *v2.RateLimitDescription
 
-	token := attr.PageToken.Token
-	if token == "" {
-		payload, rateLimitData, err = o.ListGroupMembers(ctx, resource.Id.Resource, 0)
-	} else {
-		payload, rateLimitData, err = o.ListGroupMembersContinue(ctx, token)
-	}
+	members, nextToken, rateLimitData, err := nextListPage(ctx, newListingSession(attr.Session, "group_grants:"+resource.Id.Resource), attr.PageToken.Token,
+		func(member dropbox.MembersPayload) string { return member.Profile.TeamMemberID },
+		func(ctx context.Context) (listPage[dropbox.MembersPayload], *v2.RateLimitDescription, error) {
+			payload, rateLimitData, err := o.ListGroupMembers(ctx, resource.Id.Resource, 0)
+			if err != nil {
+				return listPage[dropbox.MembersPayload]{}, rateLimitData, err
+			}
+			return listPage[dropbox.MembersPayload]{payload.Members, payload.Cursor, payload.HasMore}, rateLimitData, nil
+		},
+		func(ctx context.Context, cursor string) (listPage[dropbox.MembersPayload], *v2.RateLimitDescription, error) {
+			payload, rateLimitData, err := o.ListGroupMembersContinue(ctx, cursor)
+			if err != nil {
+				return listPage[dropbox.MembersPayload]{}, rateLimitData, err
+			}
+			return listPage[dropbox.MembersPayload]{payload.Members, payload.Cursor, payload.HasMore}, rateLimitData, nil
+		},
+	)
 
 	var outAnnotations annotations.Annotations
 	outAnnotations.WithRateLimiting(rateLimitData)
+	if dropbox.HasErrorTag(err, "group_not_found") {
+		ctxzap.Extract(ctx).Info("dropbox-connector: group was deleted during sync, skipping its grants",
+			zap.String("group_id", resource.Id.Resource),
+		)
+		return nil, &resourceSdk.SyncOpResults{
+			Annotations: outAnnotations,
+		}, nil
+	}
 	if err != nil {
 		return nil, &resourceSdk.SyncOpResults{
 			Annotations: outAnnotations,
 		}, fmt.Errorf("error listing group members: %w", err)
 	}
 
-	for _, user := range payload.Members {
+	for _, user := range members {
 		principalId, err := resourceSdk.NewResourceID(userResourceType, user.Profile.TeamMemberID)
 		if err != nil {
 			return nil, &resourceSdk.SyncOpResults{
@@ -156,13 +176,8 @@ func (o *groupBuilder) Grants(ctx context.Context, resource *v2.Resource, attr r
 		outGrants = append(outGrants, nextGrant)
 	}
 
-	var cursor string
-	if payload.HasMore {
-		cursor = payload.Cursor
-	}
-
 	return outGrants, &resourceSdk.SyncOpResults{
-		NextPageToken: cursor,
+		NextPageToken: nextToken,
 		Annotations:   outAnnotations,
 	}, nil
 }
@@ -0,0 +1,33 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	resourceSdk "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGroupBuilder_Grants_EndsForDeletedGroup verifies that a group deleted
+// mid-sync ends its grants instead of failing the sync.
+func TestGroupBuilder_Grants_EndsForDeletedGroup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/2/team/groups/members/list/continue", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(`{"error_summary": "group_not_found/..", "error": {".tag": "group_not_found"}}`))
+	}))
+	defer server.Close()
+
+	builder := newGroupBuilder(newTestConnector(t, server).client)
+	group := &v2.Resource{Id: &v2.ResourceId{ResourceType: groupResourceType.Id, Resource: "g:1"}}
+
+	grants, results, err := builder.Grants(context.Background(), group, resourceSdk.SyncOpAttrs{PageToken: pagination.Token{Token: "cursor-1"}})
+	require.NoError(t, err)
+	require.Empty(t, grants)
+	require.Empty(t, results.NextPageToken)
+}
@@ -0,0 +1,89 @@
+package connector
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/conductorone/baton-dropbox/pkg/connector/dropbox"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// lastLoginLookback is how far before the inactivity cutoff
+// list_inactive_members reads sign-ins, to report when inactive members were
+// last seen.
+const lastLoginLookback = 90 * 24 * time.Hour
+
+// inactiveMembersPageSize is how many team members one list_inactive_members
+// call checks. Each member's sign-ins are a separate event log query, so it
+// bounds the requests a call makes.
+const inactiveMembersPageSize = 50
+
+// inactiveMembersPageToken is the page token of list_inactive_members: the
+// cutoff fixed by the listing's first call and the token of the members
+// listing. The SDK hands actions no session store, so there is no last-login
+// index to consult; each call reads its members' sign-ins from the event log.
+type inactiveMembersPageToken struct {
+	// Cutoff is the Unix time before which a member's last sign-in makes
+	// them inactive.
+	Cutoff       int64  `json:"cutoff"`
+	MembersToken string `json:"members_token,omitempty"`
+}
+
+// unmarshalInactiveMembersPageToken decodes token; an empty token starts a
+// listing.
+func unmarshalInactiveMembersPageToken(token string) (*inactiveMembersPageToken, error) {
+	pt := &inactiveMembersPageToken{}
+	if token == "" {
+		return pt, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, pt); err != nil {
+		return nil, err
+	}
+	return pt, nil
+}
+
+func (pt *inactiveMembersPageToken) marshal() (string, error) {
+	data, err := json.Marshal(pt)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// memberLastLogin returns the newest successful sign-in of the member with
+// accountID from lastLoginLookback before cutoff on, or the zero time if
+// there is none. Events come oldest first, so it stops at the first sign-in
+// at or after cutoff, which is enough to know the member is active.
+func memberLastLogin(ctx context.Context, client *dropbox.Client, accountID string, cutoff time.Time) (time.Time, *v2.RateLimitDescription, error) {
+	var last time.Time
+	payload, rateLimitData, err := client.GetAccountEvents(ctx, accountID, loginSuccessEventType, cutoff.Add(-lastLoginLookback), 0)
+	for err == nil {
+		for _, e := range payload.Events {
+			// The account filter also matches events the member only
+			// took part in.
+			user := e.Actor.UserInfo()
+			if user == nil || user.AccountID != accountID {
+				continue
+			}
+			if occurredAt, parseErr := time.Parse(dropbox.TimestampFormat, e.Timestamp); parseErr == nil && occurredAt.After(last) {
+				last = occurredAt
+			}
+		}
+		if !last.Before(cutoff) || !payload.HasMore {
+			break
+		}
+		payload, rateLimitData, err = client.GetTeamEventsContinue(ctx, payload.Cursor)
+	}
+	if err != nil {
+		return time.Time{}, rateLimitData, fmt.Errorf("dropbox-connector: failed to list member login events: %w", err)
+	}
+	return last, rateLimitData, nil
+}
@@ -0,0 +1,119 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-dropbox/pkg/connector/dropbox"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestConnector_ListInactiveMembers(t *testing.T) {
+	now := time.Now().UTC()
+	longAgo := now.Add(-365 * 24 * time.Hour).Format(dropbox.TimestampFormat)
+	member := func(id, status, joinedOn string) string {
+		return fmt.Sprintf(`{"profile": {"team_member_id": "dbmid:%s", "account_id": "dbid:%s", "email": "%s@example.com", "status": {".tag": %q}, "membership_type": {".tag": "full"}, "joined_on": %q}}`,
+			id, id, id, status, joinedOn)
+	}
+	login := func(accountID string, at time.Time) string {
+		return fmt.Sprintf(`{"timestamp": %q, "event_category": {".tag": "logins"}, "event_type": {".tag": "login_success"},
+			"actor": {".tag": "user", "user": {".tag": "team_member", "account_id": %q}}}`, at.Format(dropbox.TimestampFormat), accountID)
+	}
+	// A sign-in before the threshold, then one by an admin the member only
+	// took part in.
+	staleLogin := now.Add(-120 * 24 * time.Hour).Truncate(time.Second)
+	recentLogin := now.Add(-24 * time.Hour)
+
+	var memberPageLimit int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/2/team_log/get_events":
+			var body dropbox.GetTeamEventsBody
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			require.Nil(t, body.Category)
+			require.Equal(t, "login_success", body.EventType.Tag)
+			start, err := time.Parse(dropbox.TimestampFormat, body.Time.StartTime)
+			require.NoError(t, err)
+			require.WithinDuration(t, now.Add(-180*24*time.Hour), start, time.Minute)
+			switch body.AccountID {
+			case "dbid:recent":
+				_, _ = fmt.Fprintf(w, `{"events": [%s], "cursor": "log", "has_more": true}`, login("dbid:recent", recentLogin))
+			case "dbid:stale":
+				_, _ = fmt.Fprintf(w, `{"events": [%s], "cursor": "stale-2", "has_more": true}`, login("dbid:stale", staleLogin))
+			default:
+				_, _ = fmt.Fprint(w, `{"events": [], "cursor": "log", "has_more": false}`)
+			}
+		case "/2/team_log/get_events/continue":
+			var body dropbox.GetTeamEventsContinueBody
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			require.Equal(t, "stale-2", body.Cursor)
+			_, _ = fmt.Fprintf(w, `{"events": [%s], "cursor": "log", "has_more": false}`, login("dbid:admin", recentLogin))
+		case "/2/team/members/list_v2":
+			var body dropbox.ListUserBody
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			memberPageLimit = body.Limit
+			_, _ = fmt.Fprintf(w, `{"members": [%s, %s, %s], "cursor": "cursor-1", "has_more": true}`,
+				member("recent", "active", longAgo),
+				member("stale", "active", longAgo),
+				member("new", "active", now.Format(dropbox.TimestampFormat)))
+		case "/2/team/members/list/continue_v2":
+			_, _ = fmt.Fprintf(w, `{"members": [%s, %s], "cursor": "cursor-2", "has_more": false}`,
+				member("suspended", "suspended", longAgo),
+				member("removed", "removed", longAgo))
+		default:
+			t.Fatalf("unexpected path %q", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	c := newTestConnector(t, server)
+
+	// listAll follows page tokens to the end of a listing.
+	listAll := func(args map[string]any) (ids, lastSeen []any, calls int) {
+		token := ""
+		for {
+			args["page_token"] = token
+			argStruct, err := structpb.NewStruct(args)
+			require.NoError(t, err)
+			result, _, err := c.listInactiveMembersActionHandler(context.Background(), argStruct)
+			require.NoError(t, err)
+			values := result.AsMap()
+			require.Equal(t, true, values["success"])
+			calls++
+			ids = append(ids, values["team_member_ids"].([]any)...)
+			lastSeen = append(lastSeen, values["last_seen"].([]any)...)
+			require.Equal(t, float64(len(values["team_member_ids"].([]any))), values["count"])
+			token = values["next_page_token"].(string)
+			if token == "" {
+				return ids, lastSeen, calls
+			}
+		}
+	}
+
+	ids, lastSeen, calls := listAll(map[string]any{"inactive_days": 90})
+	require.Equal(t, []any{"dbmid:stale"}, ids)
+	require.Equal(t, []any{staleLogin.Format(dropbox.TimestampFormat)}, lastSeen)
+	require.Equal(t, 2, calls)
+	require.Equal(t, inactiveMembersPageSize, memberPageLimit)
+
+	ids, lastSeen, _ = listAll(map[string]any{"inactive_days": 90, "status": "all"})
+	require.Equal(t, []any{"dbmid:stale", "dbmid:suspended"}, ids)
+	require.Equal(t, []any{staleLogin.Format(dropbox.TimestampFormat), ""}, lastSeen)
+
+	argStruct, err := structpb.NewStruct(map[string]any{"inactive_days": 90, "status": "deleted"})
+	require.NoError(t, err)
+	_, _, err = c.listInactiveMembersActionHandler(context.Background(), argStruct)
+	require.ErrorContains(t, err, "status must be one of")
+
+	argStruct, err = structpb.NewStruct(map[string]any{"inactive_days": 90, "page_token": "not a token"})
+	require.NoError(t, err)
+	_, _, err = c.listInactiveMembersActionHandler(context.Background(), argStruct)
+	require.ErrorContains(t, err, "page_token")
+}
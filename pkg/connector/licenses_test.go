@@ -29,7 +29,7 @@ func TestUserBuilder_Grants_FullMembershipGrantsLicense(t *testing.T) {
 		Email:          "user@example.com",
 		Status:         dropbox.Tag{Tag: "active"},
 		MembershipType: dropbox.Tag{Tag: "full"},
-	}, nil)
+	}, 0, nil)
 	require.NoError(t, err)
 
 	o := &userBuilder{syncLicenses: true}
@@ -49,7 +49,7 @@ func TestUserBuilder_Grants_LimitedMembershipNoLicense(t *testing.T) {
 		Email:          "limited@example.com",
 		Status:         dropbox.Tag{Tag: "active"},
 		MembershipType: dropbox.Tag{Tag: "limited"},
-	}, nil)
+	}, 0, nil)
 	require.NoError(t, err)
 
 	o := &userBuilder{syncLicenses: true}
@@ -86,7 +86,7 @@ func TestUserBuilder_Grants_SeatStatusMatrix(t *testing.T) {
 				Email:          "user@example.com",
 				Status:         dropbox.Tag{Tag: tc.status},
 				MembershipType: dropbox.Tag{Tag: tc.membershipType},
-			}, nil)
+			}, 0, nil)
 			require.NoError(t, err)
 
 			grants, _, err := o.Grants(context.Background(), res, resourceSdk.SyncOpAttrs{})
@@ -0,0 +1,218 @@
+package connector
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/conductorone/baton-dropbox/pkg/connector/dropbox"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/session"
+	"github.com/conductorone/baton-sdk/pkg/types/sessions"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// maxCursorRestarts bounds how many times one listing restarts after Dropbox
+// rejects its cursor, so a listing whose cursors keep expiring still fails
+// rather than looping.
+const maxCursorRestarts = 3
+
+// listPageToken is the page token of a listing paged with a Dropbox *_continue
+// endpoint. Cursors expire on long syncs; when Dropbox rejects one, the
+// listing restarts from the first page and skips the items it already
+// returned. Their IDs are kept in the sync's session store, one entry per
+// page, rather than in the token, so the token stays small however long the
+// listing. Items are recognized by ID rather than position, since members or
+// groups added or removed in the meantime shift the ones after them.
+type listPageToken struct {
+	Cursor string `json:"cursor,omitempty"`
+	// Pages is how many pages of returned IDs are in the session store.
+	Pages int `json:"pages,omitempty"`
+	// Listed is how many items were returned. Without a session store a
+	// restarted listing skips that many instead, and Skip counts down those
+	// still to skip.
+	Listed   int `json:"listed,omitempty"`
+	Skip     int `json:"skip,omitempty"`
+	Restarts int `json:"restarts,omitempty"`
+}
+
+// listingSession is where a listing records the IDs it returned: the sync's
+// session store, under a prefix naming the listing. With a nil store nothing
+// is recorded and a restarted listing resumes by position.
+type listingSession struct {
+	ss     sessions.SessionStore
+	prefix string
+}
+
+func newListingSession(ss sessions.SessionStore, listing string) listingSession {
+	return listingSession{ss: ss, prefix: "listing:" + listing}
+}
+
+// record stores the IDs of the page numbered page.
+func (s listingSession) record(ctx context.Context, page int, ids []string) error {
+	if s.ss == nil {
+		return nil
+	}
+	return session.SetJSON(ctx, s.ss, strconv.Itoa(page), ids, sessions.WithPrefix(s.prefix))
+}
+
+// returned returns the IDs recorded for the first pages pages.
+func (s listingSession) returned(ctx context.Context, pages int) (map[string]bool, error) {
+	keys := make([]string, 0, pages)
+	for page := range pages {
+		keys = append(keys, strconv.Itoa(page))
+	}
+	recorded, err := session.GetManyJSON[[]string](ctx, s.ss, keys, sessions.WithPrefix(s.prefix))
+	if err != nil {
+		return nil, err
+	}
+
+	returned := map[string]bool{}
+	for _, ids := range recorded {
+		for _, id := range ids {
+			returned[id] = true
+		}
+	}
+	return returned, nil
+}
+
+// listPage is one page of a listing.
+type listPage[T any] struct {
+	items   []T
+	cursor  string
+	hasMore bool
+}
+
+// unmarshalListPageToken decodes token. Tokens from before cursors were
+// wrapped are bare Dropbox cursors and are kept as such.
+func unmarshalListPageToken(token string) *listPageToken {
+	pt := &listPageToken{}
+	if token == "" {
+		return pt
+	}
+
+	data, err := base64.StdEncoding.DecodeString(token)
+	if err != nil || json.Unmarshal(data, pt) != nil || pt.Cursor == "" {
+		return &listPageToken{Cursor: token}
+	}
+	return pt
+}
+
+func (pt *listPageToken) marshal() (string, error) {
+	data, err := json.Marshal(pt)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// isCursorReset reports whether err is Dropbox rejecting a pagination cursor.
+func isCursorReset(err error) bool {
+	return dropbox.HasErrorTag(err, "reset") || dropbox.HasErrorTag(err, "invalid_cursor")
+}
+
+// nextListPage fetches the page after token with first or next, restarting
+// from the first page if Dropbox rejects the cursor. It returns the items,
+// identified by id, not returned before and the token of the following page,
+// "" after the last.
+func nextListPage[T any](
+	ctx context.Context,
+	listing listingSession,
+	token string,
+	id func(T) string,
+	first func(ctx context.Context) (listPage[T], *v2.RateLimitDescription, error),
+	next func(ctx context.Context, cursor string) (listPage[T], *v2.RateLimitDescription, error),
+) ([]T, string, *v2.RateLimitDescription, error) {
+	pt := unmarshalListPageToken(token)
+
+	var page listPage[T]
+	var rateLimitData *v2.RateLimitDescription
+	var err error
+	if pt.Cursor == "" {
+		page, rateLimitData, err = first(ctx)
+	} else {
+		page, rateLimitData, err = next(ctx, pt.Cursor)
+		if isCursorReset(err) && pt.Restarts < maxCursorRestarts {
+			ctxzap.Extract(ctx).Warn("dropbox-connector: pagination cursor expired, restarting listing",
+				zap.Int("already_listed", pt.Listed),
+				zap.Error(err),
+			)
+			pt = &listPageToken{Pages: pt.Pages, Listed: pt.Listed, Skip: pt.Listed, Restarts: pt.Restarts + 1}
+			page, rateLimitData, err = first(ctx)
+		}
+	}
+	if err != nil {
+		return nil, "", rateLimitData, err
+	}
+
+	// Only a restarted listing can repeat items, so the recorded IDs are only
+	// read after a restart.
+	var returned map[string]bool
+	if pt.Restarts > 0 && listing.ss != nil {
+		returned, err = listing.returned(ctx, pt.Pages)
+		if err != nil {
+			return nil, "", rateLimitData, fmt.Errorf("dropbox-connector: failed to read listed IDs from session: %w", err)
+		}
+	}
+
+	items := make([]T, 0, len(page.items))
+	ids := make([]string, 0, len(page.items))
+	for _, item := range page.items {
+		switch {
+		case returned != nil:
+			if returned[id(item)] {
+				continue
+			}
+		case pt.Skip > 0:
+			pt.Skip--
+			continue
+		}
+		items = append(items, item)
+		ids = append(ids, id(item))
+	}
+	pt.Listed += len(items)
+
+	if !page.hasMore {
+		return items, "", rateLimitData, nil
+	}
+	if len(ids) > 0 {
+		if err := listing.record(ctx, pt.Pages, ids); err != nil {
+			return nil, "", rateLimitData, fmt.Errorf("dropbox-connector: failed to store listed IDs in session: %w", err)
+		}
+		if listing.ss != nil {
+			pt.Pages++
+		}
+	}
+	pt.Cursor = page.cursor
+	nextToken, err := pt.marshal()
+	if err != nil {
+		return nil, "", rateLimitData, fmt.Errorf("dropbox-connector: failed to marshal page token: %w", err)
+	}
+	return items, nextToken, rateLimitData, nil
+}
+
+// nextMembersPage returns the team members after token, including removed
+// members (see DefaultListUserBody), in pages of limit members; 0 uses the
+// client's page size.
+func nextMembersPage(ctx context.Context, client *dropbox.Client, listing listingSession, limit int, token string) ([]dropbox.UserPayload, string, *v2.RateLimitDescription, error) {
+	return nextListPage(ctx, listing, token,
+		func(member dropbox.UserPayload) string { return member.Profile.TeamMemberID },
+		func(ctx context.Context) (listPage[dropbox.UserPayload], *v2.RateLimitDescription, error) {
+			payload, rateLimitData, err := client.ListUsers(ctx, limit)
+			if err != nil {
+				return listPage[dropbox.UserPayload]{}, rateLimitData, err
+			}
+			return listPage[dropbox.UserPayload]{payload.Members, payload.Cursor, payload.HasMore}, rateLimitData, nil
+		},
+		func(ctx context.Context, cursor string) (listPage[dropbox.UserPayload], *v2.RateLimitDescription, error) {
+			payload, rateLimitData, err := client.ListUsersContinue(ctx, cursor)
+			if err != nil {
+				return listPage[dropbox.UserPayload]{}, rateLimitData, err
+			}
+			return listPage[dropbox.UserPayload]{payload.Members, payload.Cursor, payload.HasMore}, rateLimitData, nil
+		},
+	)
+}
@@ -34,11 +34,21 @@ const (
 // since it requires the events.read scope and can be a high-volume stream on
 // active teams.
 type loginEventFeed struct {
-	client *dropbox.Client
+	client      *dropbox.Client
+	aggregation loginAggregation
 }
 
-func newLoginEventFeed(client *dropbox.Client) *loginEventFeed {
-	return &loginEventFeed{client: client}
+// loginAggregation collapses a member's sign-ins to the newest one. With a
+// zero window, sign-ins are collapsed within each page read; otherwise within
+// fixed windows of that length, whose events share an ID per member and are
+// emitted once the window has closed and the pass has read past its end.
+type loginAggregation struct {
+	enabled bool
+	window  time.Duration
+}
+
+func newLoginEventFeed(client *dropbox.Client, aggregation loginAggregation) *loginEventFeed {
+	return &loginEventFeed{client: client, aggregation: aggregation}
 }
 
 func (f *loginEventFeed) EventFeedMetadata(_ context.Context) *v2.EventFeedMetadata {
@@ -58,6 +68,55 @@ type loginEventPageToken struct {
 	LatestEventSeen string `json:"latest_event_seen,omitempty"`
 	NextPageToken   string `json:"next_page_token,omitempty"`
 	StartAt         string `json:"start_at,omitempty"`
+	// HeldFrom is the start of the earliest aggregation window whose
+	// sign-ins were held back in this pass because it hadn't closed. The next
+	// pass starts there so they are read again.
+	HeldFrom string `json:"held_from,omitempty"`
+	// Pending holds each member's newest sign-in in closed aggregation
+	// windows the pass hasn't read past yet, whose sign-ins may continue on
+	// the next page.
+	Pending []loginSignIn `json:"pending,omitempty"`
+}
+
+// loginSignIn is a successful sign-in read from the logins category.
+type loginSignIn struct {
+	ID           string    `json:"id,omitempty"`
+	TeamMemberID string    `json:"team_member_id"`
+	Email        string    `json:"email,omitempty"`
+	DisplayName  string    `json:"display_name,omitempty"`
+	OccurredAt   time.Time `json:"occurred_at"`
+}
+
+// usageEvent returns the sign-in as a usage event of the Dropbox app.
+func (s loginSignIn) usageEvent() (*v2.Event, error) {
+	userTrait, err := resourceSdk.NewUserTrait(resourceSdk.WithEmail(s.Email, true))
+	if err != nil {
+		return nil, err
+	}
+
+	return &v2.Event{
+		Id:         s.ID,
+		OccurredAt: timestamppb.New(s.OccurredAt),
+		Event: &v2.Event_UsageEvent{
+			UsageEvent: &v2.UsageEvent{
+				TargetResource: &v2.Resource{
+					Id: &v2.ResourceId{
+						ResourceType: appResourceType.Id,
+						Resource:     dropboxAppResourceID,
+					},
+					DisplayName: dropboxAppDisplayName,
+				},
+				ActorResource: &v2.Resource{
+					Id: &v2.ResourceId{
+						ResourceType: userResourceType.Id,
+						Resource:     s.TeamMemberID,
+					},
+					DisplayName: s.DisplayName,
+					Annotations: annotations.New(userTrait),
+				},
+			},
+		},
+	}, nil
 }
 
 func unmarshalLoginEventPageToken(token *pagination.StreamToken, defaultStart *timestamppb.Timestamp) (*loginEventPageToken, error) {
@@ -72,6 +131,13 @@ func unmarshalLoginEventPageToken(token *pagination.StreamToken, defaultStart *t
 		}
 	}
 
+	pt.setDefaults(defaultStart)
+	return pt, nil
+}
+
+// setDefaults starts a token without a sync window at defaultStart, or at the
+// catch-up window if defaultStart is nil.
+func (pt *loginEventPageToken) setDefaults(defaultStart *timestamppb.Timestamp) {
 	if pt.StartAt == "" {
 		if defaultStart == nil {
 			defaultStart = timestamppb.New(time.Now().Add(-defaultCatchUpWindow))
@@ -81,8 +147,31 @@ func unmarshalLoginEventPageToken(token *pagination.StreamToken, defaultStart *t
 	if pt.LatestEventSeen == "" {
 		pt.LatestEventSeen = pt.StartAt
 	}
+}
 
-	return pt, nil
+// finishPage records the cursor of the next page or, once the log is drained,
+// starts the next sync window at the newest event seen, or at the earliest
+// held-back aggregation window if that is older.
+func (pt *loginEventPageToken) finishPage(payload *dropbox.GetTeamEventsPayload) {
+	pt.NextPageToken = payload.Cursor
+	if !payload.HasMore {
+		pt.StartAt = pt.LatestEventSeen
+		if pt.HeldFrom != "" && pt.HeldFrom < pt.StartAt {
+			pt.StartAt = pt.HeldFrom
+		}
+		pt.LatestEventSeen = ""
+		pt.NextPageToken = ""
+		pt.HeldFrom = ""
+	}
+}
+
+// holdFrom records that the sign-ins of the window starting at windowStart
+// were held back.
+func (pt *loginEventPageToken) holdFrom(windowStart time.Time) {
+	start := windowStart.UTC().Format(dropbox.TimestampFormat)
+	if pt.HeldFrom == "" || start < pt.HeldFrom {
+		pt.HeldFrom = start
+	}
 }
 
 func (pt *loginEventPageToken) marshal() (string, error) {
@@ -131,7 +220,8 @@ func (f *loginEventFeed) ListEvents(
 		latestEvent = time.Unix(0, 0)
 	}
 
-	events := make([]*v2.Event, 0, len(payload.Events))
+	var pageLatest time.Time
+	signIns := make([]loginSignIn, 0, len(payload.Events))
 	for _, e := range payload.Events {
 		occurredAt, parseErr := time.Parse(dropbox.TimestampFormat, e.Timestamp)
 		if parseErr != nil {
@@ -149,6 +239,9 @@ func (f *loginEventFeed) ListEvents(
 			latestEvent = occurredAt
 			cursor.LatestEventSeen = occurredAt.UTC().Format(dropbox.TimestampFormat)
 		}
+		if occurredAt.After(pageLatest) {
+			pageLatest = occurredAt
+		}
 
 		// The "logins" category also includes logouts, failures, and password
 		// resets; only successful sign-ins count as usage.
@@ -164,46 +257,43 @@ func (f *loginEventFeed) ListEvents(
 			continue
 		}
 
-		userTrait, traitErr := resourceSdk.NewUserTrait(resourceSdk.WithEmail(userInfo.Email, true))
-		if traitErr != nil {
-			return nil, nil, outAnnotations, fmt.Errorf("dropbox-connector: failed to build user trait for login event: %w", traitErr)
-		}
-
-		events = append(events, &v2.Event{
-			// Dropbox's team_log events carry no documented unique event ID, so the
-			// actor + timestamp pair is used as a synthetic one (second-granularity
-			// timestamps mean same-second repeat logins by one user could collide).
-			Id:         fmt.Sprintf("%s-%s", userInfo.TeamMemberID, e.Timestamp),
-			OccurredAt: timestamppb.New(occurredAt),
-			Event: &v2.Event_UsageEvent{
-				UsageEvent: &v2.UsageEvent{
-					TargetResource: &v2.Resource{
-						Id: &v2.ResourceId{
-							ResourceType: appResourceType.Id,
-							Resource:     dropboxAppResourceID,
-						},
-						DisplayName: dropboxAppDisplayName,
-					},
-					ActorResource: &v2.Resource{
-						Id: &v2.ResourceId{
-							ResourceType: userResourceType.Id,
-							Resource:     userInfo.TeamMemberID,
-						},
-						DisplayName: userInfo.DisplayName,
-						Annotations: annotations.New(userTrait),
-					},
-				},
-			},
+		signIns = append(signIns, loginSignIn{
+			// Dropbox's team_log events carry no documented unique event ID, so one
+			// is derived from the event's content; same-second sign-ins by one
+			// user from different sessions or locations still get distinct IDs.
+			ID:           e.ID(),
+			TeamMemberID: userInfo.TeamMemberID,
+			Email:        userInfo.Email,
+			DisplayName:  userInfo.DisplayName,
+			OccurredAt:   occurredAt,
 		})
 	}
 
-	cursor.NextPageToken = payload.Cursor
-	if !payload.HasMore {
-		cursor.StartAt = cursor.LatestEventSeen
-		cursor.LatestEventSeen = ""
-		cursor.NextPageToken = ""
+	if f.aggregation.enabled {
+		// Events come oldest first, so a window has been read in full once an
+		// event at or after its end has been seen, or the pass has ended.
+		readThrough := pageLatest
+		if !payload.HasMore {
+			readThrough = time.Now()
+		}
+		var heldFrom time.Time
+		signIns, cursor.Pending, heldFrom = f.aggregation.collapse(append(cursor.Pending, signIns...), readThrough, time.Now())
+		if !heldFrom.IsZero() {
+			cursor.holdFrom(heldFrom)
+		}
+	}
+
+	events := make([]*v2.Event, 0, len(signIns))
+	for _, signIn := range signIns {
+		event, err := signIn.usageEvent()
+		if err != nil {
+			return nil, nil, outAnnotations, fmt.Errorf("dropbox-connector: failed to build user trait for login event: %w", err)
+		}
+		events = append(events, event)
 	}
 
+	cursor.finishPage(payload)
+
 	cursorToken, err := cursor.marshal()
 	if err != nil {
 		return nil, nil, outAnnotations, fmt.Errorf("dropbox-connector: failed to marshal login event cursor: %w", err)
@@ -214,3 +304,58 @@ func (f *loginEventFeed) ListEvents(
 		HasMore: payload.HasMore,
 	}, outAnnotations, nil
 }
+
+// collapse keeps each member's newest sign-in per window, in the order the
+// members first signed in. In window mode the event ID names the member and
+// window, so a window spanning passes yields one event per member; otherwise
+// the newest sign-in keeps its own ID.
+//
+// Since a window's event has one ID, it must carry the window's newest
+// sign-in when first emitted: a later, newer sign-in would be dropped as a
+// duplicate. So collapse emits a window only once it has closed at now and
+// the log has been read through its end. Closed windows not read through yet
+// are returned as pending, to be collapsed again with the next page. Sign-ins
+// in windows still open at now are held back, and collapse returns the start
+// of the earliest such window for the next pass to read from; it returns the
+// zero time if none was held back.
+func (a loginAggregation) collapse(signIns []loginSignIn, readThrough, now time.Time) ([]loginSignIn, []loginSignIn, time.Time) {
+	var order []string
+	var heldFrom time.Time
+	newest := map[string]loginSignIn{}
+	for _, signIn := range signIns {
+		key := signIn.TeamMemberID
+		if a.window > 0 {
+			windowStart := signIn.OccurredAt.Truncate(a.window).UTC()
+			if windowStart.Add(a.window).After(now) {
+				if heldFrom.IsZero() || windowStart.Before(heldFrom) {
+					heldFrom = windowStart
+				}
+				continue
+			}
+			key = fmt.Sprintf("%s-%s", key, windowStart.Format(dropbox.TimestampFormat))
+		}
+
+		current, ok := newest[key]
+		if !ok {
+			order = append(order, key)
+		}
+		if !ok || signIn.OccurredAt.After(current.OccurredAt) {
+			newest[key] = signIn
+		}
+	}
+
+	collapsed := make([]loginSignIn, 0, len(order))
+	var pending []loginSignIn
+	for _, key := range order {
+		signIn := newest[key]
+		if a.window > 0 {
+			if signIn.OccurredAt.Truncate(a.window).Add(a.window).After(readThrough) {
+				pending = append(pending, signIn)
+				continue
+			}
+			signIn.ID = "login-" + key
+		}
+		collapsed = append(collapsed, signIn)
+	}
+	return collapsed, pending, heldFrom
+}
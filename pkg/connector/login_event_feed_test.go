@@ -59,7 +59,7 @@ func newTestLoginEventFeed(t *testing.T, server *httptest.Server) *loginEventFee
 	require.NoError(t, err)
 	client.TokenSource = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "test-token"})
 
-	return newLoginEventFeed(client)
+	return newLoginEventFeed(client, loginAggregation{})
 }
 
 func TestLoginEventFeed_ListEvents_FiltersToSuccessfulUserLogins(t *testing.T) {
@@ -276,3 +276,169 @@ func mustParseDropboxTime(t *testing.T, s string) time.Time {
 	require.NoError(t, err)
 	return ts
 }
+
+func TestLoginEventFeed_ListEvents_AggregatesLoginsPerWindow(t *testing.T) {
+	login := func(timestamp, teamMemberID string) dropbox.TeamEvent {
+		return dropbox.TeamEvent{
+			Timestamp:     timestamp,
+			EventCategory: dropbox.Tag{Tag: "logins"},
+			EventType:     dropbox.Tag{Tag: "login_success"},
+			Actor: dropbox.ActorLogInfo{
+				Tag:  "user",
+				User: &dropbox.UserLogInfo{TeamMemberID: teamMemberID, Email: teamMemberID + "@example.com"},
+			},
+		}
+	}
+	payload := dropbox.GetTeamEventsPayload{
+		Events: []dropbox.TeamEvent{
+			login("2024-01-01T08:00:00Z", "dbmid:1"),
+			login("2024-01-01T09:00:00Z", "dbmid:2"),
+			login("2024-01-01T17:30:00Z", "dbmid:1"),
+			login("2024-01-02T08:00:00Z", "dbmid:1"),
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(payload))
+	}))
+	defer server.Close()
+
+	feed := newTestLoginEventFeed(t, server)
+	feed.aggregation = loginAggregation{enabled: true, window: 24 * time.Hour}
+
+	events, _, _, err := feed.ListEvents(context.Background(), nil, nil)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	require.Equal(t, "login-dbmid:1-2024-01-01T00:00:00Z", events[0].GetId())
+	require.Equal(t, time.Date(2024, 1, 1, 17, 30, 0, 0, time.UTC), events[0].GetOccurredAt().AsTime())
+	require.Equal(t, "login-dbmid:2-2024-01-01T00:00:00Z", events[1].GetId())
+	require.Equal(t, "login-dbmid:1-2024-01-02T00:00:00Z", events[2].GetId())
+
+	// Without a window, a member's sign-ins collapse across the whole page.
+	feed.aggregation = loginAggregation{enabled: true}
+	events, _, _, err = feed.ListEvents(context.Background(), nil, nil)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, "dbmid:1", events[0].GetUsageEvent().GetActorResource().GetId().GetResource())
+	require.Equal(t, time.Date(2024, 1, 2, 8, 0, 0, 0, time.UTC), events[0].GetOccurredAt().AsTime())
+	require.Equal(t, "dbmid:2", events[1].GetUsageEvent().GetActorResource().GetId().GetResource())
+}
+
+// TestLoginEventFeed_ListEvents_AggregatesWindowSplitAcrossPages verifies that
+// a window whose sign-ins span two pages is emitted once, with its newest
+// sign-in, after the pass has read past the window's end.
+func TestLoginEventFeed_ListEvents_AggregatesWindowSplitAcrossPages(t *testing.T) {
+	login := func(timestamp, teamMemberID string) dropbox.TeamEvent {
+		return dropbox.TeamEvent{
+			Timestamp:     timestamp,
+			EventCategory: dropbox.Tag{Tag: "logins"},
+			EventType:     dropbox.Tag{Tag: "login_success"},
+			Actor: dropbox.ActorLogInfo{
+				Tag:  "user",
+				User: &dropbox.UserLogInfo{TeamMemberID: teamMemberID, Email: teamMemberID + "@example.com"},
+			},
+		}
+	}
+	firstPage := dropbox.GetTeamEventsPayload{
+		Events: []dropbox.TeamEvent{
+			login("2024-01-01T08:00:00Z", "dbmid:1"),
+			login("2024-01-01T09:00:00Z", "dbmid:2"),
+		},
+		Cursor:  "page-2-cursor",
+		HasMore: true,
+	}
+	secondPage := dropbox.GetTeamEventsPayload{
+		Events: []dropbox.TeamEvent{
+			login("2024-01-01T17:30:00Z", "dbmid:1"),
+			login("2024-01-02T08:00:00Z", "dbmid:2"),
+		},
+		Cursor:  "page-3-cursor",
+		HasMore: true,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/2/team_log/get_events":
+			require.NoError(t, json.NewEncoder(w).Encode(firstPage))
+		case "/2/team_log/get_events/continue":
+			require.NoError(t, json.NewEncoder(w).Encode(secondPage))
+		default:
+			t.Fatalf("unexpected request path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	feed := newTestLoginEventFeed(t, server)
+	feed.aggregation = loginAggregation{enabled: true, window: 24 * time.Hour}
+
+	// The first page ends inside the window, so nothing is emitted yet.
+	events, state, _, err := feed.ListEvents(context.Background(), nil, nil)
+	require.NoError(t, err)
+	require.Empty(t, events)
+	cursor, err := unmarshalLoginEventPageToken(&pagination.StreamToken{Cursor: state.Cursor}, nil)
+	require.NoError(t, err)
+	require.Len(t, cursor.Pending, 2)
+
+	// The second page reads past the window's end; the window's newest
+	// sign-ins are emitted and the next window is pending.
+	events, state, _, err = feed.ListEvents(context.Background(), nil, &pagination.StreamToken{Cursor: state.Cursor})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, "login-dbmid:1-2024-01-01T00:00:00Z", events[0].GetId())
+	require.Equal(t, time.Date(2024, 1, 1, 17, 30, 0, 0, time.UTC), events[0].GetOccurredAt().AsTime())
+	require.Equal(t, "login-dbmid:2-2024-01-01T00:00:00Z", events[1].GetId())
+	cursor, err = unmarshalLoginEventPageToken(&pagination.StreamToken{Cursor: state.Cursor}, nil)
+	require.NoError(t, err)
+	require.Len(t, cursor.Pending, 1)
+	require.Equal(t, "dbmid:2", cursor.Pending[0].TeamMemberID)
+}
+
+// TestLoginEventFeed_ListEvents_HoldsBackOpenWindows verifies that sign-ins in
+// an aggregation window that hasn't closed are not emitted yet, and that the
+// next pass starts at that window so its newest sign-in is emitted once it
+// closes.
+func TestLoginEventFeed_ListEvents_HoldsBackOpenWindows(t *testing.T) {
+	window := time.Hour
+	openWindow := time.Now().Truncate(window).UTC()
+	closedWindow := openWindow.Add(-2 * window)
+
+	login := func(at time.Time) dropbox.TeamEvent {
+		return dropbox.TeamEvent{
+			Timestamp:     at.Format(dropbox.TimestampFormat),
+			EventCategory: dropbox.Tag{Tag: "logins"},
+			EventType:     dropbox.Tag{Tag: "login_success"},
+			Actor: dropbox.ActorLogInfo{
+				Tag:  "user",
+				User: &dropbox.UserLogInfo{TeamMemberID: "dbmid:1", Email: "alice@example.com"},
+			},
+		}
+	}
+	payload := dropbox.GetTeamEventsPayload{
+		Events: []dropbox.TeamEvent{
+			login(closedWindow.Add(10 * time.Minute)),
+			login(openWindow),
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(payload))
+	}))
+	defer server.Close()
+
+	feed := newTestLoginEventFeed(t, server)
+	feed.aggregation = loginAggregation{enabled: true, window: window}
+
+	events, state, _, err := feed.ListEvents(context.Background(), timestamppb.New(closedWindow), nil)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, "login-dbmid:1-"+closedWindow.Format(dropbox.TimestampFormat), events[0].GetId())
+
+	cursor, err := unmarshalLoginEventPageToken(&pagination.StreamToken{Cursor: state.Cursor}, nil)
+	require.NoError(t, err)
+	require.Equal(t, openWindow.Format(dropbox.TimestampFormat), cursor.StartAt)
+	require.Empty(t, cursor.HeldFrom)
+}
@@ -0,0 +1,136 @@
+package connector
+
+import (
+	"context"
+	"slices"
+
+	"github.com/conductorone/baton-dropbox/pkg/connector/dropbox"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const resourceChangeEventFeedID = "dropbox_resource_change_feed"
+
+// resourceChangeCategories are the team_log categories read by
+// resourceChangeFeed, in the order they are read. team_folders is read only
+// when folders are synced (see folderBuilder).
+var resourceChangeCategories = []string{"members", "groups", "team_folders"}
+
+// memberChangeEventTypes are the "members" events after which a member's
+// resource is out of date. member_change_status covers members being added
+// (invited or joined), removed, suspended and unsuspended.
+var memberChangeEventTypes = map[string]bool{
+	"member_add_name":      true,
+	"member_change_name":   true,
+	"member_change_email":  true,
+	"member_change_status": true,
+}
+
+// groupChangeEventTypes are the "groups" events after which a group's
+// resource is out of date.
+var groupChangeEventTypes = map[string]bool{
+	"group_create": true,
+	"group_rename": true,
+	"group_delete": true,
+}
+
+// folderChangeEventTypes are the "team_folders" events after which a team
+// folder's resource is out of date. team_folder_change_status covers team
+// folders being archived and unarchived.
+var folderChangeEventTypes = map[string]bool{
+	"team_folder_create":             true,
+	"team_folder_rename":             true,
+	"team_folder_change_status":      true,
+	"team_folder_downgrade":          true,
+	"team_folder_permanently_delete": true,
+}
+
+// resourceChangeFeed emits ResourceChangeEvents for members, groups and team
+// folders changed in Dropbox, derived from the team event audit log, so C1 can
+// refresh them between full syncs. Gated behind the
+// sync-resource-change-events config flag since it requires the events.read
+// scope.
+type resourceChangeFeed struct {
+	client     *dropbox.Client
+	categories []string
+}
+
+// newResourceChangeFeed returns a feed reading resourceChangeCategories, less
+// team_folders unless syncFolders is set.
+func newResourceChangeFeed(client *dropbox.Client, syncFolders bool) *resourceChangeFeed {
+	categories := resourceChangeCategories
+	if !syncFolders {
+		categories = slices.DeleteFunc(slices.Clone(categories), func(category string) bool {
+			return category == "team_folders"
+		})
+	}
+	return &resourceChangeFeed{client: client, categories: categories}
+}
+
+func (f *resourceChangeFeed) EventFeedMetadata(_ context.Context) *v2.EventFeedMetadata {
+	return &v2.EventFeedMetadata{
+		Id: resourceChangeEventFeedID,
+		SupportedEventTypes: []v2.EventType{
+			v2.EventType_EVENT_TYPE_RESOURCE_CHANGE,
+		},
+	}
+}
+
+func (f *resourceChangeFeed) ListEvents(
+	ctx context.Context,
+	startAt *timestamppb.Timestamp,
+	pToken *pagination.StreamToken,
+) ([]*v2.Event, *pagination.StreamState, annotations.Annotations, error) {
+	teamEvents, state, rateLimitData, err := nextCategoryEventPage(ctx, f.client, f.categories, startAt, pToken)
+
+	var outAnnotations annotations.Annotations
+	outAnnotations.WithRateLimiting(rateLimitData)
+
+	if err != nil {
+		return nil, nil, outAnnotations, err
+	}
+
+	events := make([]*v2.Event, 0, len(teamEvents))
+	for _, e := range teamEvents {
+		resourceID := changedResource(e.TeamEvent)
+		if resourceID == nil {
+			continue
+		}
+
+		events = append(events, &v2.Event{
+			Id:         e.ID(),
+			OccurredAt: timestamppb.New(e.OccurredAt),
+			Event: &v2.Event_ResourceChangeEvent{
+				ResourceChangeEvent: &v2.ResourceChangeEvent{
+					ResourceId: resourceID,
+				},
+			},
+		})
+	}
+
+	return events, state, outAnnotations, nil
+}
+
+// changedResource returns the member, group or team folder an event changed,
+// or nil if the event doesn't change a synced resource.
+func changedResource(e dropbox.TeamEvent) *v2.ResourceId {
+	switch {
+	case memberChangeEventTypes[e.EventType.Tag]:
+		if member := e.Context.TeamMember(); member != nil {
+			return &v2.ResourceId{ResourceType: userResourceType.Id, Resource: member.TeamMemberID}
+		}
+	case groupChangeEventTypes[e.EventType.Tag]:
+		if group := e.Group(); group != nil {
+			return &v2.ResourceId{ResourceType: groupResourceType.Id, Resource: group.GroupID}
+		}
+	case folderChangeEventTypes[e.EventType.Tag]:
+		for _, asset := range e.Assets {
+			if nsID, _, ok := sharedFolderOf(asset); ok {
+				return &v2.ResourceId{ResourceType: folderResourceType.Id, Resource: nsID}
+			}
+		}
+	}
+	return nil
+}
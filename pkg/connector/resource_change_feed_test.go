@@ -0,0 +1,111 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-dropbox/pkg/connector/dropbox"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const memberEventsJSON = `{"events": [
+	{"timestamp": "2024-01-01T10:00:00Z", "event_category": {".tag": "members"}, "event_type": {".tag": "member_change_status"},
+	 "actor": {".tag": "admin", "admin": {".tag": "team_member", "team_member_id": "dbmid:admin"}},
+	 "context": {".tag": "team_member", "team_member_id": "dbmid:1", "email": "alice@example.com"}},
+	{"timestamp": "2024-01-01T11:00:00Z", "event_category": {".tag": "members"}, "event_type": {".tag": "member_change_email"},
+	 "actor": {".tag": "user", "user": {".tag": "team_member", "team_member_id": "dbmid:2"}},
+	 "context": {".tag": "team_member", "team_member_id": "dbmid:2"}},
+	{"timestamp": "2024-01-01T12:00:00Z", "event_category": {".tag": "members"}, "event_type": {".tag": "member_set_profile_photo"},
+	 "actor": {".tag": "user", "user": {".tag": "team_member", "team_member_id": "dbmid:3"}},
+	 "context": {".tag": "team_member", "team_member_id": "dbmid:3"}}
+], "cursor": "members-cursor", "has_more": false}`
+
+const groupEventsJSON = `{"events": [
+	{"timestamp": "2024-01-02T10:00:00Z", "event_category": {".tag": "groups"}, "event_type": {".tag": "group_rename"},
+	 "actor": {".tag": "admin", "admin": {".tag": "team_member", "team_member_id": "dbmid:admin"}},
+	 "context": {".tag": "team"},
+	 "participants": [{".tag": "group", "group_id": "g:1", "display_name": "Engineering"}]},
+	{"timestamp": "2024-01-02T11:00:00Z", "event_category": {".tag": "groups"}, "event_type": {".tag": "group_add_member"},
+	 "actor": {".tag": "admin", "admin": {".tag": "team_member", "team_member_id": "dbmid:admin"}},
+	 "context": {".tag": "team_member", "team_member_id": "dbmid:1"},
+	 "participants": [{".tag": "group", "group_id": "g:1", "display_name": "Engineering"}]}
+], "cursor": "groups-cursor", "has_more": false}`
+
+const teamFolderEventsJSON = `{"events": [
+	{"timestamp": "2024-01-03T10:00:00Z", "event_category": {".tag": "team_folders"}, "event_type": {".tag": "team_folder_rename"},
+	 "actor": {".tag": "admin", "admin": {".tag": "team_member", "team_member_id": "dbmid:admin"}},
+	 "assets": [{".tag": "folder", "display_name": "Launch", "path": {"contextual": "/Launch",
+		"namespace_relative": {"ns_id": "1001", "relative_path": "/", "is_shared_namespace": true}}}]}
+], "cursor": "team-folders-cursor", "has_more": false}`
+
+// TestResourceChangeFeed_ListEvents_ReadsEachCategoryInTurn verifies that the
+// feed reads the members, groups and team_folders categories one page at a
+// time, emits a change for each member, group or team folder changed, and
+// keeps a sync window per category.
+func TestResourceChangeFeed_ListEvents_ReadsEachCategoryInTurn(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/2/team_log/get_events", r.URL.Path)
+		var body dropbox.GetTeamEventsBody
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		w.Header().Set("Content-Type", "application/json")
+		switch body.Category.Tag {
+		case "members":
+			_, _ = w.Write([]byte(memberEventsJSON))
+		case "groups":
+			_, _ = w.Write([]byte(groupEventsJSON))
+		case "team_folders":
+			_, _ = w.Write([]byte(teamFolderEventsJSON))
+		default:
+			t.Fatalf("unexpected category %q", body.Category.Tag)
+		}
+	}))
+	defer server.Close()
+
+	client, err := dropbox.NewClient(context.Background(), dropbox.Config{BaseURL: server.URL})
+	require.NoError(t, err)
+	client.TokenSource = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "test-token"})
+	feed := newResourceChangeFeed(client, true)
+	startAt := timestamppb.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	events, state, _, err := feed.ListEvents(context.Background(), startAt, nil)
+	require.NoError(t, err)
+	require.True(t, state.HasMore, "the groups category is still to be read")
+	require.Len(t, events, 2)
+	require.Equal(t, userResourceType.Id, events[0].GetResourceChangeEvent().GetResourceId().GetResourceType())
+	require.Equal(t, "dbmid:1", events[0].GetResourceChangeEvent().GetResourceId().GetResource())
+	require.Equal(t, "dbmid:2", events[1].GetResourceChangeEvent().GetResourceId().GetResource())
+
+	events, state, _, err = feed.ListEvents(context.Background(), startAt, &pagination.StreamToken{Cursor: state.Cursor})
+	require.NoError(t, err)
+	require.True(t, state.HasMore, "the team_folders category is still to be read")
+	require.Len(t, events, 1)
+	require.Equal(t, groupResourceType.Id, events[0].GetResourceChangeEvent().GetResourceId().GetResourceType())
+	require.Equal(t, "g:1", events[0].GetResourceChangeEvent().GetResourceId().GetResource())
+
+	events, state, _, err = feed.ListEvents(context.Background(), startAt, &pagination.StreamToken{Cursor: state.Cursor})
+	require.NoError(t, err)
+	require.False(t, state.HasMore)
+	require.Len(t, events, 1)
+	require.Equal(t, folderResourceType.Id, events[0].GetResourceChangeEvent().GetResourceId().GetResourceType())
+	require.Equal(t, "1001", events[0].GetResourceChangeEvent().GetResourceId().GetResource())
+
+	pt, err := unmarshalCategoryPageToken(&pagination.StreamToken{Cursor: state.Cursor}, resourceChangeCategories)
+	require.NoError(t, err)
+	require.Equal(t, 0, pt.Category)
+	require.Equal(t, "2024-01-01T12:00:00Z", pt.Categories["members"].StartAt)
+	require.Equal(t, "2024-01-02T11:00:00Z", pt.Categories["groups"].StartAt)
+	require.Equal(t, "2024-01-03T10:00:00Z", pt.Categories["team_folders"].StartAt)
+}
+
+func TestNewResourceChangeFeed_SkipsTeamFoldersUnlessFoldersAreSynced(t *testing.T) {
+	require.Equal(t, []string{"members", "groups"}, newResourceChangeFeed(nil, false).categories)
+	require.Equal(t, resourceChangeCategories, newResourceChangeFeed(nil, true).categories)
+}
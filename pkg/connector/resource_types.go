@@ -1,6 +1,8 @@
 package connector
 
 import (
+	"slices"
+
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 )
@@ -73,6 +75,25 @@ func capabilityPermissions(permissions ...string) *v2.CapabilityPermissions {
 	return v2.CapabilityPermissions_builder{Permissions: perms}.Build()
 }
 
+// requiredScopes returns the scopes declared by resourceTypes'
+// CapabilityPermissions annotations, deduplicated in declaration order.
+func requiredScopes(resourceTypes ...*v2.ResourceType) []string {
+	var scopes []string
+	for _, rt := range resourceTypes {
+		perms := &v2.CapabilityPermissions{}
+		annos := annotations.Annotations(rt.GetAnnotations())
+		if ok, err := annos.Pick(perms); err != nil || !ok {
+			continue
+		}
+		for _, p := range perms.GetPermissions() {
+			if !slices.Contains(scopes, p.GetPermission()) {
+				scopes = append(scopes, p.GetPermission())
+			}
+		}
+	}
+	return scopes
+}
+
 // appResourceType represents the single, static "Dropbox" app resource that
 // loginEventFeed's usage events target. Always synced; see app.go.
 var appResourceType = &v2.ResourceType{
@@ -86,3 +107,65 @@ var appResourceType = &v2.ResourceType{
 		&v2.SkipEntitlements{},
 	),
 }
+
+// folderResourceType is the team folders and shared folders of the team,
+// keyed by namespace ID, which folderActivityFeed's usage events and
+// resourceChangeFeed's folder changes target. Synced with
+// sync-folder-activity or sync-resource-change-events.
+//
+// Scopes (per the Dropbox API spec): team_data.member reads
+// team/namespaces/list.
+var folderResourceType = &v2.ResourceType{
+	Id:          "folder",
+	DisplayName: "Folder",
+	Annotations: annotations.New(
+		&v2.SkipEntitlementsAndGrants{},
+		capabilityPermissions("team_data.member"),
+	),
+}
+
+// signInInsightResourceType holds the security insights derived from the
+// team event log's logins category by signInInsightBuilder. Synced only with
+// sync-sign-in-insights.
+//
+// Scopes (per the Dropbox API spec): events.read reads team_log/get_events.
+var signInInsightResourceType = &v2.ResourceType{
+	Id:          "sign_in_insight",
+	DisplayName: "Sign-in Insight",
+	Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_SECURITY_INSIGHT},
+	Annotations: annotations.New(
+		&v2.SkipEntitlementsAndGrants{},
+		capabilityPermissions("events.read"),
+	),
+}
+
+// teamResourceType is the Dropbox team itself, with its effective feature
+// values on the profile. It targets teamPolicyChangeResourceType's insights.
+// Synced only with sync-team-policies.
+//
+// Scopes (per the Dropbox API spec): team_info.read reads team/get_info and
+// team/features/get_values.
+var teamResourceType = &v2.ResourceType{
+	Id:          "team",
+	DisplayName: "Team",
+	Annotations: annotations.New(
+		&v2.SkipEntitlementsAndGrants{},
+		capabilityPermissions("team_info.read"),
+	),
+}
+
+// teamPolicyChangeResourceType holds the security insights derived from the
+// team event log's team_policies category by teamPolicyChangeBuilder. Synced
+// only with sync-team-policies.
+//
+// Scopes (per the Dropbox API spec): events.read reads team_log/get_events,
+// and team_info.read reads team/get_info for the team the insights target.
+var teamPolicyChangeResourceType = &v2.ResourceType{
+	Id:          "team_policy_change",
+	DisplayName: "Team Policy Change",
+	Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_SECURITY_INSIGHT},
+	Annotations: annotations.New(
+		&v2.SkipEntitlementsAndGrants{},
+		capabilityPermissions("events.read", "team_info.read"),
+	),
+}
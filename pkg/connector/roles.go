@@ -48,16 +48,7 @@ func (o *roleBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId,
 	token := attr.PageToken.Token
 	logger.Debug("Starting Roles List", zap.String("token", token))
 	outResources := []*v2.Resource{}
-
-	var payload *dropbox.ListUsersPayload
-	var rateLimitData *v2.RateLimitDescription
-	var err error
-
-	if token == "" {
-		payload, rateLimitData, err = o.ListUsers(ctx, limit)
-	} else {
-		payload, rateLimitData, err = o.ListUsersContinue(ctx, token)
-	}
+	members, nextToken, rateLimitData, err := nextMembersPage(ctx, o.Client, newListingSession(attr.Session, "roles"), 0, token)
 
 	var outAnnotations annotations.Annotations
 	outAnnotations.WithRateLimiting(rateLimitData)
@@ -68,7 +59,7 @@ func (o *roleBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId,
 		}, fmt.Errorf("error listing users: %w", err)
 	}
 
-	for _, user := range payload.Members {
+	for _, user := range members {
 		for _, role := range user.Roles {
 			roleResource, err := roleResource(role, parentResourceID)
 			if err != nil {
@@ -80,13 +71,8 @@ func (o *roleBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId,
 		}
 	}
 
-	var cursor string
-	if payload.HasMore {
-		cursor = payload.Cursor
-	}
-
 	return outResources, &resourceSdk.SyncOpResults{
-		NextPageToken: cursor,
+		NextPageToken: nextToken,
 		Annotations:   outAnnotations,
 	}, nil
 }
@@ -105,17 +91,8 @@ func (o *roleBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ r
 
 func (o *roleBuilder) Grants(ctx context.Context, resource *v2.Resource, attr resourceSdk.SyncOpAttrs) ([]*v2.Grant, *resourceSdk.SyncOpResults, error) {
 	var outGrants []*v2.Grant
+	members, nextToken, rateLimitData, err := nextMembersPage(ctx, o.Client, newListingSession(attr.Session, "role_grants:"+resource.Id.Resource), 0, attr.PageToken.Token)
 
-	var payload *dropbox.ListUsersPayload
-	var rateLimitData *v2.RateLimitDescription
-	var err error
-
-	token := attr.PageToken.Token
-	if token == "" {
-		payload, rateLimitData, err = o.ListUsers(ctx, limit)
-	} else {
-		payload, rateLimitData, err = o.ListUsersContinue(ctx, token)
-	}
 	var outAnnotations annotations.Annotations
 	outAnnotations.WithRateLimiting(rateLimitData)
 
@@ -125,7 +102,7 @@ func (o *roleBuilder) Grants(ctx context.Context, resource *v2.Resource, attr re
 		}, fmt.Errorf("error listing users: %w", err)
 	}
 
-	for _, user := range payload.Members {
+	for _, user := range members {
 		if !user.HasRole(resource.Id.Resource) {
 			continue
 		}
@@ -143,12 +120,8 @@ func (o *roleBuilder) Grants(ctx context.Context, resource *v2.Resource, attr re
 		outGrants = append(outGrants, nextGrant)
 	}
 
-	var cursor string
-	if payload.HasMore {
-		cursor = payload.Cursor
-	}
 	return outGrants, &resourceSdk.SyncOpResults{
-		NextPageToken: cursor,
+		NextPageToken: nextToken,
 		Annotations:   outAnnotations,
 	}, nil
 }
@@ -0,0 +1,250 @@
+package connector
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// requiredScopes lists the scopes the connector needs with its current
+// configuration.
+func (c *Connector) requiredScopes() []string {
+	scopes := requiredScopes(userResourceType, roleResourceType, groupResourceType, licenseResourceType)
+	if c.syncUserLastLogin || c.syncResourceChanges || c.syncGrantEvents || c.syncFolderActivity || c.syncSignInInsights || c.syncTwoStepVerification || c.syncTeamPolicies {
+		scopes = append(scopes, "events.read")
+	}
+	if c.syncsFolders() {
+		scopes = append(scopes, "team_data.member")
+	}
+	if c.syncTeamPolicies {
+		scopes = append(scopes, "team_info.read")
+	}
+	if c.syncDeviceActivity {
+		scopes = append(scopes, "sessions.list")
+	}
+	return scopes
+}
+
+// discoverScopes records which required scopes the app was not granted, so the
+// features needing them are skipped rather than failing the sync. If the
+// scopes can't be checked, every scope is assumed granted and the API reports
+// whatever is missing.
+func (c *Connector) discoverScopes(ctx context.Context) {
+	l := ctxzap.Extract(ctx)
+
+	missing, unverified, err := c.client.CheckScopes(ctx, c.requiredScopes())
+	if err != nil {
+		l.Warn("dropbox-connector: failed to discover granted scopes, assuming all are granted", zap.Error(err))
+		return
+	}
+	if len(unverified) > 0 {
+		l.Debug("dropbox-connector: could not verify scopes, the token did not report its granted scopes",
+			zap.Strings("scopes", unverified))
+	}
+	c.missingScopes = missing
+
+	for _, skipped := range c.skippedCapabilities(ctx) {
+		l.Warn("dropbox-connector: skipping capability, the Dropbox app is missing scopes",
+			zap.String("capability", skipped.capability),
+			zap.Strings("missing_scopes", skipped.missingScopes),
+		)
+	}
+}
+
+// scopesGranted reports whether none of scopes is known to be missing.
+func (c *Connector) scopesGranted(scopes ...string) bool {
+	return len(c.missingOf(scopes)) == 0
+}
+
+func (c *Connector) missingOf(scopes []string) []string {
+	var missing []string
+	for _, scope := range scopes {
+		if slices.Contains(c.missingScopes, scope) {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
+// readScopes returns the scopes a resource type needs to be synced: all of its
+// scopes but the *.write and *.delete ones provisioning needs.
+func readScopes(rt *v2.ResourceType) []string {
+	var read []string
+	for _, scope := range requiredScopes(rt) {
+		if !strings.HasSuffix(scope, ".write") && !strings.HasSuffix(scope, ".delete") {
+			read = append(read, scope)
+		}
+	}
+	return read
+}
+
+// provisioningScopes are the scopes each provisioning capability of a
+// resource type needs, so that a missing scope hides only the capabilities
+// that use it.
+type provisioningScopes struct {
+	// grants covers Grant and Revoke.
+	grants        []string
+	createAccount []string
+	delete        []string
+}
+
+var resourceProvisioningScopes = map[string]provisioningScopes{
+	userResourceType.Id:  {createAccount: []string{"members.write"}, delete: []string{"members.delete"}},
+	groupResourceType.Id: {grants: []string{"groups.write"}},
+	roleResourceType.Id:  {grants: []string{"members.write"}},
+}
+
+// readOnlySyncer exposes only the ResourceSyncerV2 methods of a builder,
+// hiding Grant/Revoke and account provisioning when their scopes are missing.
+type readOnlySyncer struct {
+	connectorbuilder.ResourceSyncerV2
+}
+
+// accountCreatorSyncer exposes a builder's account creation but not its
+// deletion.
+type accountCreatorSyncer struct {
+	connectorbuilder.ResourceSyncerV2
+	connectorbuilder.AccountManagerLimited
+}
+
+// accountDeleterSyncer exposes a builder's deletion but not its account
+// creation.
+type accountDeleterSyncer struct {
+	connectorbuilder.ResourceSyncerV2
+	connectorbuilder.ResourceDeleterLimited
+}
+
+// limitProvisioning hides the provisioning capabilities of builder whose
+// scopes weren't granted. No resource type both grants entitlements and
+// provisions accounts, so grants are kept or hidden as a whole.
+func (c *Connector) limitProvisioning(ctx context.Context, builder connectorbuilder.ResourceSyncerV2) connectorbuilder.ResourceSyncerV2 {
+	scopes := resourceProvisioningScopes[builder.ResourceType(ctx).GetId()]
+	grants := c.scopesGranted(scopes.grants...)
+	create := c.scopesGranted(scopes.createAccount...)
+	del := c.scopesGranted(scopes.delete...)
+
+	switch {
+	case grants && create && del:
+		return builder
+	case grants && create:
+		if creator, ok := builder.(connectorbuilder.AccountManagerLimited); ok {
+			return accountCreatorSyncer{builder, creator}
+		}
+	case grants && del:
+		if deleter, ok := builder.(connectorbuilder.ResourceDeleterLimited); ok {
+			return accountDeleterSyncer{builder, deleter}
+		}
+	}
+	return readOnlySyncer{builder}
+}
+
+// skippedCapability is a feature disabled because the app lacks scopes.
+type skippedCapability struct {
+	capability    string
+	missingScopes []string
+}
+
+// annotation describes the skipped capability as a warning for Validate's
+// response.
+func (s skippedCapability) annotation() *structpb.Struct {
+	missing := make([]any, 0, len(s.missingScopes))
+	for _, scope := range s.missingScopes {
+		missing = append(missing, scope)
+	}
+
+	// NewStruct can't fail for strings and lists of strings.
+	warning, _ := structpb.NewStruct(map[string]any{
+		"warning":        "capability skipped, the Dropbox app is missing scopes",
+		"capability":     s.capability,
+		"missing_scopes": missing,
+	})
+	return warning
+}
+
+// syncCapabilityNames names the resource syncers whose warnings would
+// otherwise only carry a resource type ID. The insight syncers need
+// events.read like the event feeds, so they are named alongside them.
+var syncCapabilityNames = map[string]string{
+	signInInsightResourceType.Id:    "sign-in insights",
+	teamPolicyChangeResourceType.Id: "team policy change insights",
+}
+
+// skippedCapabilities lists the syncers, provisioning paths, event feeds and
+// actions that are disabled because of missing scopes.
+func (c *Connector) skippedCapabilities(ctx context.Context) []skippedCapability {
+	var skipped []skippedCapability
+
+	for _, builder := range c.allResourceSyncers() {
+		rt := builder.ResourceType(ctx)
+		if missing := c.missingOf(readScopes(rt)); len(missing) > 0 {
+			name, ok := syncCapabilityNames[rt.GetId()]
+			if !ok {
+				name = rt.GetId() + " sync"
+			}
+			skipped = append(skipped, skippedCapability{name, missing})
+			continue
+		}
+		scopes := resourceProvisioningScopes[rt.GetId()]
+		if missing := c.missingOf(scopes.grants); len(missing) > 0 {
+			skipped = append(skipped, skippedCapability{rt.GetId() + " provisioning", missing})
+		}
+		if missing := c.missingOf(scopes.createAccount); len(missing) > 0 {
+			skipped = append(skipped, skippedCapability{rt.GetId() + " account creation", missing})
+		}
+		if missing := c.missingOf(scopes.delete); len(missing) > 0 {
+			skipped = append(skipped, skippedCapability{rt.GetId() + " deletion", missing})
+		}
+	}
+
+	if missing := c.missingOf([]string{"events.read"}); len(missing) > 0 {
+		if c.syncUserLastLogin {
+			skipped = append(skipped, skippedCapability{"last-login event feed", missing})
+		}
+		if c.syncResourceChanges {
+			skipped = append(skipped, skippedCapability{"resource change event feed", missing})
+		}
+		if c.syncGrantEvents {
+			skipped = append(skipped, skippedCapability{"grant event feed", missing})
+		}
+		if c.syncTwoStepVerification {
+			skipped = append(skipped, skippedCapability{"two-step verification status", missing})
+		}
+	}
+
+	if c.syncFolderActivity {
+		if missing := c.missingOf([]string{"events.read", "team_data.member"}); len(missing) > 0 {
+			skipped = append(skipped, skippedCapability{"folder activity event feed", missing})
+		}
+	}
+
+	if c.syncDeviceActivity {
+		if missing := c.missingOf([]string{"sessions.list"}); len(missing) > 0 {
+			skipped = append(skipped, skippedCapability{"device activity event feed", missing})
+		}
+	}
+
+	for _, action := range c.globalActions() {
+		if missing := c.missingOf(action.scopes); len(missing) > 0 {
+			skipped = append(skipped, skippedCapability{action.schema.GetName() + " action", missing})
+		}
+	}
+
+	return skipped
+}
+
+// skippedCapabilityAnnotations returns a warning annotation per skipped
+// capability.
+func (c *Connector) skippedCapabilityAnnotations(ctx context.Context) annotations.Annotations {
+	var annos annotations.Annotations
+	for _, skipped := range c.skippedCapabilities(ctx) {
+		annos.Append(skipped.annotation())
+	}
+	return annos
+}
@@ -0,0 +1,421 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/conductorone/baton-dropbox/pkg/connector/dropbox"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/session"
+	resourceSdk "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/conductorone/baton-sdk/pkg/types/sessions"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// Kinds of sign-in insight.
+const (
+	repeatedLoginFailuresInsight = "repeated_login_failures"
+	newCountryInsight            = "new_country"
+	ssoBypassInsight             = "sso_bypass"
+	signInAsInsight              = "sign_in_as"
+	emmErrorInsight              = "emm_error"
+	ssoErrorInsight              = "sso_error"
+)
+
+const (
+	// failedLoginThreshold is how many failed sign-ins within the insight
+	// window flag an account; twice as many raise the severity.
+	failedLoginThreshold = 5
+
+	// defaultSignInInsightWindow is how far back the logins category is read
+	// when no window is configured.
+	defaultSignInInsightWindow = 7 * 24 * time.Hour
+
+	// signInBaselineLookback is how far before the insight window sign-ins
+	// are read to learn the countries members usually sign in from.
+	signInBaselineLookback = 30 * 24 * time.Hour
+
+	// signInInsightSessionPrefix namespaces the insight aggregation carried
+	// between List pages in the session store.
+	signInInsightSessionPrefix = "sign_in_insights"
+	signInAggregationKey       = "aggregation"
+)
+
+// signInInsightBuilder syncs security insights derived from the logins
+// category of the team event log: repeated failed sign-ins on an account,
+// sign-ins from a country the member hadn't signed in from before, password
+// sign-ins on a team requiring SSO, admins signing in as the member, and EMM
+// and SSO errors. Each insight records the IP address, location and login
+// method of its newest event. Gated behind the sync-sign-in-insights config
+// flag since it requires the events.read scope.
+type signInInsightBuilder struct {
+	client *dropbox.Client
+	window time.Duration
+	// ssoRequired flags password sign-ins as SSO bypasses. Dropbox doesn't
+	// expose the team's SSO policy through its API, so it is configured.
+	ssoRequired bool
+}
+
+func newSignInInsightBuilder(client *dropbox.Client, window time.Duration, ssoRequired bool) *signInInsightBuilder {
+	if window <= 0 {
+		window = defaultSignInInsightWindow
+	}
+	return &signInInsightBuilder{client: client, window: window, ssoRequired: ssoRequired}
+}
+
+func (b *signInInsightBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return signInInsightResourceType
+}
+
+// List reads a page of the logins category per call, from the baseline
+// lookback to now, and keeps the aggregation in the session store between
+// pages, since an insight can depend on every sign-in of the window. The page
+// token is the Dropbox cursor, and the insights are returned with the last
+// page. Without a session store the whole range is read in one call.
+func (b *signInInsightBuilder) List(ctx context.Context, _ *v2.ResourceId, attr resourceSdk.SyncOpAttrs) ([]*v2.Resource, *resourceSdk.SyncOpResults, error) {
+	var outAnnotations annotations.Annotations
+
+	aggregation, cursor, err := b.loadAggregation(ctx, attr)
+	if err != nil {
+		return nil, &resourceSdk.SyncOpResults{
+			Annotations: outAnnotations,
+		}, err
+	}
+
+	var payload *dropbox.GetTeamEventsPayload
+	var rateLimitData *v2.RateLimitDescription
+	if cursor == "" {
+		start := aggregation.WindowStart.Add(-signInBaselineLookback)
+		payload, rateLimitData, err = b.client.GetTeamEvents(ctx, loginsEventCategory, &start, 0)
+	} else {
+		payload, rateLimitData, err = b.client.GetTeamEventsContinue(ctx, cursor)
+	}
+	for err == nil {
+		aggregation.add(ctx, payload.Events, b.ssoRequired)
+		if !payload.HasMore || attr.Session != nil {
+			break
+		}
+		payload, rateLimitData, err = b.client.GetTeamEventsContinue(ctx, payload.Cursor)
+	}
+	outAnnotations.WithRateLimiting(rateLimitData)
+	if err != nil {
+		return nil, &resourceSdk.SyncOpResults{
+			Annotations: outAnnotations,
+		}, fmt.Errorf("dropbox-connector: failed to list team login events: %w", err)
+	}
+
+	if payload.HasMore {
+		if err := session.SetJSON(ctx, attr.Session, signInAggregationKey, aggregation, sessions.WithPrefix(signInInsightSessionPrefix)); err != nil {
+			return nil, &resourceSdk.SyncOpResults{
+				Annotations: outAnnotations,
+			}, fmt.Errorf("dropbox-connector: failed to store sign-in insight aggregation in session: %w", err)
+		}
+		return nil, &resourceSdk.SyncOpResults{
+			NextPageToken: payload.Cursor,
+			Annotations:   outAnnotations,
+		}, nil
+	}
+
+	if attr.Session != nil {
+		if err := attr.Session.Delete(ctx, signInAggregationKey, sessions.WithPrefix(signInInsightSessionPrefix)); err != nil {
+			return nil, &resourceSdk.SyncOpResults{
+				Annotations: outAnnotations,
+			}, fmt.Errorf("dropbox-connector: failed to clear sign-in insight aggregation from session: %w", err)
+		}
+	}
+
+	return b.resources(ctx, aggregation.signals()), &resourceSdk.SyncOpResults{
+		Annotations: outAnnotations,
+	}, nil
+}
+
+// loadAggregation returns the aggregation and Dropbox cursor of the List in
+// progress, or a new aggregation for a window ending now.
+func (b *signInInsightBuilder) loadAggregation(ctx context.Context, attr resourceSdk.SyncOpAttrs) (*signInAggregation, string, error) {
+	if attr.PageToken.Token == "" || attr.Session == nil {
+		return newSignInAggregation(time.Now().Add(-b.window)), "", nil
+	}
+
+	aggregation, ok, err := session.GetJSON[signInAggregation](ctx, attr.Session, signInAggregationKey, sessions.WithPrefix(signInInsightSessionPrefix))
+	if err != nil {
+		return nil, "", fmt.Errorf("dropbox-connector: failed to read sign-in insight aggregation from session: %w", err)
+	}
+	if !ok {
+		// The session was lost; start the window over.
+		ctxzap.Extract(ctx).Warn("dropbox-connector: sign-in insight aggregation missing from session, restarting listing")
+		return newSignInAggregation(time.Now().Add(-b.window)), "", nil
+	}
+	return &aggregation, attr.PageToken.Token, nil
+}
+
+func (b *signInInsightBuilder) resources(ctx context.Context, signals []*signInSignal) []*v2.Resource {
+	l := ctxzap.Extract(ctx)
+
+	resources := make([]*v2.Resource, 0, len(signals))
+	for _, signal := range signals {
+		resource, err := signal.resource(b.window)
+		if err != nil {
+			l.Debug("dropbox-connector: skipping sign-in insight", zap.String("insight", signal.id()), zap.Error(err))
+			continue
+		}
+		resources = append(resources, resource)
+	}
+	return resources
+}
+
+func (b *signInInsightBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ resourceSdk.SyncOpAttrs) ([]*v2.Entitlement, *resourceSdk.SyncOpResults, error) {
+	return nil, nil, nil
+}
+
+func (b *signInInsightBuilder) Grants(_ context.Context, _ *v2.Resource, _ resourceSdk.SyncOpAttrs) ([]*v2.Grant, *resourceSdk.SyncOpResults, error) {
+	return nil, nil, nil
+}
+
+// signInSignal is one insight about a user, accumulated over the window.
+type signInSignal struct {
+	Kind    string               `json:"kind"`
+	User    *dropbox.UserLogInfo `json:"user"`
+	Country string               `json:"country,omitempty"`
+	// Detail names who or what else was involved, e.g. the admin who signed
+	// in as the user.
+	Detail    string    `json:"detail,omitempty"`
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	// Origin and LoginMethod are those of the newest event.
+	Origin      *dropbox.OriginLogInfo `json:"origin,omitempty"`
+	LoginMethod string                 `json:"login_method,omitempty"`
+}
+
+// userKey identifies a user by team member ID or, for non-members, email.
+func userKey(user *dropbox.UserLogInfo) string {
+	if user.TeamMemberID != "" {
+		return user.TeamMemberID
+	}
+	return user.Email
+}
+
+func (s *signInSignal) id() string {
+	if s.Country != "" {
+		return fmt.Sprintf("%s:%s:%s", s.Kind, userKey(s.User), s.Country)
+	}
+	return fmt.Sprintf("%s:%s", s.Kind, userKey(s.User))
+}
+
+// signInAggregation accumulates insights from login events, which Dropbox
+// returns oldest first, across pages.
+type signInAggregation struct {
+	// WindowStart is the start of the insight window. Earlier sign-ins only
+	// add to Countries.
+	WindowStart time.Time `json:"window_start"`
+	// Countries holds the countries each member has signed in from so far.
+	Countries map[string]map[string]bool `json:"countries"`
+	Signals   map[string]*signInSignal   `json:"signals"`
+}
+
+func newSignInAggregation(windowStart time.Time) *signInAggregation {
+	return &signInAggregation{
+		WindowStart: windowStart,
+		Countries:   map[string]map[string]bool{},
+		Signals:     map[string]*signInSignal{},
+	}
+}
+
+func (a *signInAggregation) record(kind string, user *dropbox.UserLogInfo, country string, occurredAt time.Time, e *dropbox.TeamEvent, details *dropbox.LoginDetails) *signInSignal {
+	signal := &signInSignal{Kind: kind, User: user, Country: country, FirstSeen: occurredAt}
+	if existing, ok := a.Signals[signal.id()]; ok {
+		signal = existing
+	} else {
+		a.Signals[signal.id()] = signal
+	}
+	signal.Count++
+	signal.LastSeen = occurredAt
+	signal.Origin = e.Origin
+	if details != nil && details.LoginMethod != nil {
+		signal.LoginMethod = details.LoginMethod.Tag
+	}
+	return signal
+}
+
+// add folds events into the aggregation.
+func (a *signInAggregation) add(ctx context.Context, events []dropbox.TeamEvent, ssoRequired bool) {
+	l := ctxzap.Extract(ctx)
+
+	for i := range events {
+		e := &events[i]
+		occurredAt, err := time.Parse(dropbox.TimestampFormat, e.Timestamp)
+		if err != nil {
+			l.Debug("dropbox-connector: skipping login event with unparseable timestamp", zap.String("timestamp", e.Timestamp), zap.Error(err))
+			continue
+		}
+		inWindow := !occurredAt.Before(a.WindowStart)
+
+		var details *dropbox.LoginDetails
+		if e.Details != nil {
+			details = &dropbox.LoginDetails{}
+			if err := e.DecodeDetails(details); err != nil {
+				details = nil
+			}
+		}
+
+		if !inWindow && e.EventType.Tag != loginSuccessEventType {
+			continue
+		}
+
+		switch e.EventType.Tag {
+		case loginSuccessEventType:
+			user := e.Actor.UserInfo()
+			if user == nil || userKey(user) == "" {
+				continue
+			}
+			if inWindow && ssoRequired && details != nil && details.LoginMethod != nil && details.LoginMethod.Tag == "password" {
+				a.record(ssoBypassInsight, user, "", occurredAt, e, details)
+			}
+			if e.Origin == nil || e.Origin.GeoLocation == nil || e.Origin.GeoLocation.Country == "" {
+				continue
+			}
+			country := e.Origin.GeoLocation.Country
+			seen, ok := a.Countries[userKey(user)]
+			if !ok {
+				// The member's first sign-in, in the baseline lookback if they
+				// signed in then, sets the baseline.
+				a.Countries[userKey(user)] = map[string]bool{country: true}
+				continue
+			}
+			if !seen[country] {
+				seen[country] = true
+				if inWindow {
+					a.record(newCountryInsight, user, country, occurredAt, e, details)
+				}
+			}
+		case "login_fail":
+			if user := e.Actor.UserInfo(); user != nil && userKey(user) != "" {
+				a.record(repeatedLoginFailuresInsight, user, "", occurredAt, e, details)
+			}
+		case "emm_error":
+			if user := e.Actor.UserInfo(); user != nil && userKey(user) != "" {
+				a.record(emmErrorInsight, user, "", occurredAt, e, details)
+			}
+		case "sso_error":
+			if user := e.Actor.UserInfo(); user != nil && userKey(user) != "" {
+				a.record(ssoErrorInsight, user, "", occurredAt, e, details)
+			}
+		case "sign_in_as_session_start":
+			member := e.Context.TeamMember()
+			if member == nil {
+				continue
+			}
+			signal := a.record(signInAsInsight, member, "", occurredAt, e, details)
+			if admin := e.Actor.UserInfo(); admin != nil {
+				signal.Detail = admin.Email
+			}
+		}
+	}
+}
+
+// signals returns the insights, sorted by ID. Insights below their threshold
+// are dropped.
+func (a *signInAggregation) signals() []*signInSignal {
+	ids := make([]string, 0, len(a.Signals))
+	for id, signal := range a.Signals {
+		if signal.Kind == repeatedLoginFailuresInsight && signal.Count < failedLoginThreshold {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	out := make([]*signInSignal, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, a.Signals[id])
+	}
+	return out
+}
+
+// issue returns the insight's issue text and severity.
+func (s *signInSignal) issue(window time.Duration) (string, string) {
+	days := int(window.Hours() / 24)
+	switch s.Kind {
+	case repeatedLoginFailuresInsight:
+		severity := "Medium"
+		if s.Count >= 2*failedLoginThreshold {
+			severity = "High"
+		}
+		return fmt.Sprintf("%d failed sign-ins in %d days", s.Count, days), severity
+	case newCountryInsight:
+		return fmt.Sprintf("Sign-in from new country %s", s.Country), "Medium"
+	case ssoBypassInsight:
+		return fmt.Sprintf("%d password sign-ins on a team requiring SSO", s.Count), "High"
+	case signInAsInsight:
+		if s.Detail != "" {
+			return fmt.Sprintf("Admin %s signed in as this member %d times", s.Detail, s.Count), "Low"
+		}
+		return fmt.Sprintf("An admin signed in as this member %d times", s.Count), "Low"
+	case emmErrorInsight:
+		return fmt.Sprintf("%d EMM sign-in errors", s.Count), "Low"
+	default:
+		return fmt.Sprintf("%d SSO sign-in errors", s.Count), "Low"
+	}
+}
+
+func (s *signInSignal) resource(window time.Duration) (*v2.Resource, error) {
+	issue, severity := s.issue(window)
+
+	var target resourceSdk.SecurityInsightTraitOption
+	switch {
+	case s.User.TeamMemberID != "":
+		target = resourceSdk.WithInsightResourceTarget(&v2.ResourceId{ResourceType: userResourceType.Id, Resource: s.User.TeamMemberID})
+	case s.User.Email != "":
+		target = resourceSdk.WithInsightUserTarget(s.User.Email)
+	default:
+		return nil, fmt.Errorf("insight has no user to target")
+	}
+
+	profile := map[string]interface{}{
+		"kind":       s.Kind,
+		"count":      s.Count,
+		"first_seen": s.FirstSeen.UTC().Format(dropbox.TimestampFormat),
+		"last_seen":  s.LastSeen.UTC().Format(dropbox.TimestampFormat),
+	}
+	if s.LoginMethod != "" {
+		profile["login_method"] = s.LoginMethod
+	}
+	if s.Origin != nil {
+		if s.Origin.AccessMethod.Tag != "" {
+			profile["access_method"] = s.Origin.AccessMethod.Tag
+		}
+		if geo := s.Origin.GeoLocation; geo != nil {
+			for key, value := range map[string]string{"ip_address": geo.IPAddress, "city": geo.City, "region": geo.Region, "country": geo.Country} {
+				if value != "" {
+					profile[key] = value
+				}
+			}
+		}
+	}
+
+	name := s.User.Email
+	if name == "" {
+		name = s.User.DisplayName
+	}
+	if name == "" {
+		name = userKey(s.User)
+	}
+
+	return resourceSdk.NewResource(
+		fmt.Sprintf("%s: %s", issue, name),
+		signInInsightResourceType,
+		s.id(),
+		resourceSdk.WithDescription(issue),
+		resourceSdk.WithResourceProfile(profile),
+		resourceSdk.WithSecurityInsightTrait(
+			resourceSdk.WithIssue(issue),
+			resourceSdk.WithIssueSeverity(severity),
+			resourceSdk.WithInsightObservedAt(s.LastSeen),
+			target,
+		),
+	)
+}
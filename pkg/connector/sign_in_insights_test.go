@@ -0,0 +1,123 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-dropbox/pkg/connector/dropbox"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	resourceSdk "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func loginEventJSON(eventType, timestamp, teamMemberID, country, ip, method string) string {
+	return fmt.Sprintf(`{"timestamp": %q, "event_category": {".tag": "logins"}, "event_type": {".tag": %q},
+		"actor": {".tag": "user", "user": {".tag": "team_member", "team_member_id": %q, "email": "%s@example.com"}},
+		"origin": {"geo_location": {"country": %q, "ip_address": %q}, "access_method": {".tag": "end_user"}},
+		"details": {".tag": "%s_details", "login_method": {".tag": %q}}}`,
+		timestamp, eventType, teamMemberID, teamMemberID, country, ip, eventType, method)
+}
+
+// TestSignInInsightBuilder_List verifies that insights are derived from the
+// sign-ins of the window, with each member's usual countries learned from the
+// baseline lookback before it, both when the log is read a page per call with
+// a session store and in one call without.
+func TestSignInInsightBuilder_List(t *testing.T) {
+	ago := func(d time.Duration) string {
+		return time.Now().Add(-d).UTC().Format(dropbox.TimestampFormat)
+	}
+	day := 24 * time.Hour
+
+	// Before the window: dave's failures don't count, and bob's sign-in from
+	// PT sets his baseline.
+	var firstPage []string
+	for i := range failedLoginThreshold {
+		firstPage = append(firstPage, loginEventJSON("login_fail", ago(10*day-time.Duration(i)*time.Minute), "dave", "US", "192.0.2.4", "password"))
+	}
+	firstPage = append(firstPage, loginEventJSON("login_success", ago(9*day), "bob", "PT", "198.51.100.7", "saml"))
+	for i := range failedLoginThreshold {
+		firstPage = append(firstPage, loginEventJSON("login_fail", ago(2*day-time.Duration(i)*time.Minute), "alice", "US", "192.0.2.1", "password"))
+	}
+	firstPage = append(firstPage, loginEventJSON("login_success", ago(2*day-time.Hour), "bob", "US", "192.0.2.2", "saml"))
+
+	secondPage := []string{
+		// PT is in bob's baseline, so it isn't new.
+		loginEventJSON("login_success", ago(day), "bob", "PT", "198.51.100.7", "password"),
+		// One failure is below the threshold.
+		loginEventJSON("login_fail", ago(day-time.Hour), "carol", "US", "192.0.2.3", "password"),
+		`{"timestamp": "` + ago(day-2*time.Hour) + `", "event_category": {".tag": "logins"}, "event_type": {".tag": "sign_in_as_session_start"},
+		  "actor": {".tag": "admin", "admin": {".tag": "team_member", "team_member_id": "admin", "email": "admin@example.com"}},
+		  "context": {".tag": "team_member", "team_member_id": "carol", "email": "carol@example.com"},
+		  "details": {".tag": "sign_in_as_session_start_details"}}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/2/team_log/get_events":
+			fmt.Fprintf(w, `{"events": [%s], "cursor": "page-2", "has_more": true}`, strings.Join(firstPage, ","))
+		case "/2/team_log/get_events/continue":
+			fmt.Fprintf(w, `{"events": [%s], "cursor": "page-3", "has_more": false}`, strings.Join(secondPage, ","))
+		default:
+			t.Fatalf("unexpected path %q", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client, err := dropbox.NewClient(context.Background(), dropbox.Config{BaseURL: server.URL})
+	require.NoError(t, err)
+	client.TokenSource = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "test-token"})
+	builder := newSignInInsightBuilder(client, 7*day, true)
+
+	ss := memorySessionStore{}
+	resources, results, err := builder.List(context.Background(), nil, resourceSdk.SyncOpAttrs{Session: ss})
+	require.NoError(t, err)
+	require.Empty(t, resources, "insights are returned with the last page")
+	require.Equal(t, "page-2", results.NextPageToken)
+
+	resources, results, err = builder.List(context.Background(), nil, resourceSdk.SyncOpAttrs{
+		Session:   ss,
+		PageToken: pagination.Token{Token: results.NextPageToken},
+	})
+	require.NoError(t, err)
+	require.Empty(t, results.NextPageToken)
+	require.Empty(t, ss, "the aggregation is cleared once the listing ends")
+
+	unpaged, _, err := builder.List(context.Background(), nil, resourceSdk.SyncOpAttrs{})
+	require.NoError(t, err)
+	require.Len(t, unpaged, len(resources))
+
+	ids := make([]string, 0, len(resources))
+	for _, r := range resources {
+		ids = append(ids, r.GetId().GetResource())
+	}
+	require.Equal(t, []string{
+		"new_country:bob:US",
+		"repeated_login_failures:alice",
+		"sign_in_as:carol",
+		"sso_bypass:bob",
+	}, ids)
+
+	newCountry := resources[0]
+	trait, err := resourceSdk.GetSecurityInsightTrait(newCountry)
+	require.NoError(t, err)
+	require.Equal(t, "Sign-in from new country US", trait.GetIssue().GetValue())
+	require.Equal(t, "bob", trait.GetResourceId().GetResource())
+	require.Equal(t, "192.0.2.2", newCountry.GetProfile().GetFields()["ip_address"].GetStringValue())
+	require.Equal(t, "saml", newCountry.GetProfile().GetFields()["login_method"].GetStringValue())
+
+	failures, err := resourceSdk.GetSecurityInsightTrait(resources[1])
+	require.NoError(t, err)
+	require.Equal(t, "5 failed sign-ins in 7 days", failures.GetIssue().GetValue())
+	require.Equal(t, "Medium", failures.GetIssue().GetSeverity())
+
+	signInAs, err := resourceSdk.GetSecurityInsightTrait(resources[2])
+	require.NoError(t, err)
+	require.Equal(t, "Admin admin@example.com signed in as this member 1 times", signInAs.GetIssue().GetValue())
+}
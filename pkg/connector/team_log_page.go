@@ -0,0 +1,143 @@
+package connector
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/conductorone/baton-dropbox/pkg/connector/dropbox"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// categoryPageToken is the cursor persisted between ListEvents calls of a
+// feed reading several team_log categories. team_log/get_events filters on a
+// single category, so each category keeps its own cursor and sync window (see
+// loginEventPageToken). Categories are read in turn; a page of one category
+// is read per call.
+type categoryPageToken struct {
+	Category   int                             `json:"category,omitempty"`
+	Categories map[string]*loginEventPageToken `json:"categories,omitempty"`
+}
+
+func unmarshalCategoryPageToken(token *pagination.StreamToken, categories []string) (*categoryPageToken, error) {
+	pt := &categoryPageToken{}
+	if token != nil && token.Cursor != "" {
+		data, err := base64.StdEncoding.DecodeString(token.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, pt); err != nil {
+			return nil, err
+		}
+	}
+	if pt.Categories == nil {
+		pt.Categories = map[string]*loginEventPageToken{}
+	}
+	if pt.Category < 0 || pt.Category >= len(categories) {
+		pt.Category = 0
+	}
+	return pt, nil
+}
+
+func (pt *categoryPageToken) marshal() (string, error) {
+	data, err := json.Marshal(pt)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// teamLogEvent is a team event with its parsed timestamp.
+type teamLogEvent struct {
+	dropbox.TeamEvent
+	OccurredAt time.Time
+}
+
+// nextCategoryEventPage reads the next page of team events from categories,
+// moving to the next category once one is drained. The stream has more until
+// every category has been drained in this round. Events with unparseable
+// timestamps are dropped.
+func nextCategoryEventPage(
+	ctx context.Context,
+	client *dropbox.Client,
+	categories []string,
+	startAt *timestamppb.Timestamp,
+	pToken *pagination.StreamToken,
+) ([]teamLogEvent, *pagination.StreamState, *v2.RateLimitDescription, error) {
+	l := ctxzap.Extract(ctx)
+
+	pt, err := unmarshalCategoryPageToken(pToken, categories)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("dropbox-connector: failed to unmarshal team event page token: %w", err)
+	}
+
+	category := categories[pt.Category]
+	cursor, ok := pt.Categories[category]
+	if !ok {
+		cursor = &loginEventPageToken{}
+		pt.Categories[category] = cursor
+	}
+	cursor.setDefaults(startAt)
+
+	var payload *dropbox.GetTeamEventsPayload
+	var rateLimitData *v2.RateLimitDescription
+
+	if cursor.NextPageToken != "" {
+		payload, rateLimitData, err = client.GetTeamEventsContinue(ctx, cursor.NextPageToken)
+	} else {
+		startTime, parseErr := time.Parse(dropbox.TimestampFormat, cursor.StartAt)
+		if parseErr != nil {
+			l.Debug("dropbox-connector: failed to parse team event start time, using default catch-up window", zap.Error(parseErr))
+			startTime = time.Now().Add(-defaultCatchUpWindow)
+		}
+		payload, rateLimitData, err = client.GetTeamEvents(ctx, category, &startTime, 0)
+	}
+	if err != nil {
+		return nil, nil, rateLimitData, fmt.Errorf("dropbox-connector: failed to list team %s events: %w", category, err)
+	}
+
+	latestEvent, err := time.Parse(dropbox.TimestampFormat, cursor.LatestEventSeen)
+	if err != nil {
+		latestEvent = time.Unix(0, 0)
+	}
+
+	events := make([]teamLogEvent, 0, len(payload.Events))
+	for _, e := range payload.Events {
+		occurredAt, parseErr := time.Parse(dropbox.TimestampFormat, e.Timestamp)
+		if parseErr != nil {
+			l.Debug("dropbox-connector: skipping team event with unparseable timestamp", zap.String("timestamp", e.Timestamp), zap.Error(parseErr))
+			continue
+		}
+		// As in loginEventFeed, every event seen advances the high-water mark,
+		// including the ones the caller ignores.
+		if occurredAt.After(latestEvent) {
+			latestEvent = occurredAt
+			cursor.LatestEventSeen = occurredAt.UTC().Format(dropbox.TimestampFormat)
+		}
+		events = append(events, teamLogEvent{TeamEvent: e, OccurredAt: occurredAt})
+	}
+
+	cursor.finishPage(payload)
+
+	hasMore := payload.HasMore
+	if !payload.HasMore {
+		pt.Category = (pt.Category + 1) % len(categories)
+		hasMore = pt.Category != 0
+	}
+
+	cursorToken, err := pt.marshal()
+	if err != nil {
+		return nil, nil, rateLimitData, fmt.Errorf("dropbox-connector: failed to marshal team event cursor: %w", err)
+	}
+
+	return events, &pagination.StreamState{
+		Cursor:  cursorToken,
+		HasMore: hasMore,
+	}, rateLimitData, nil
+}
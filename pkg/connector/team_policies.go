@@ -0,0 +1,327 @@
+package connector
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/conductorone/baton-dropbox/pkg/connector/dropbox"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	resourceSdk "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+const teamPoliciesEventCategory = "team_policies"
+
+// defaultTeamPolicyInsightWindow is how far back the team_policies category
+// is read when no window is configured.
+const defaultTeamPolicyInsightWindow = 30 * 24 * time.Hour
+
+// teamFeatures are the features read from team/features/get_values, which
+// are all the features Dropbox exposes there.
+var teamFeatures = []string{
+	"upload_api_rate_limit",
+	"has_team_shared_dropbox",
+	"has_team_file_events",
+	"has_team_selective_sync",
+	"has_distinct_member_homes",
+}
+
+// teamPolicySeverities maps event type prefixes to the severity of the
+// policies they change. Other policies are Low.
+var teamPolicySeverities = []struct {
+	prefix   string
+	severity string
+}{
+	{"sso_", "High"},
+	{"tfa_", "High"},
+	{"password_", "High"},
+	{"sharing_", "Medium"},
+	{"device_approvals_", "Medium"},
+	{"sign_in_as_", "Medium"},
+	{"emm_", "Medium"},
+}
+
+// teamBuilder syncs the Dropbox team as a single resource whose profile holds
+// its effective feature values, for compliance reviews and as the target of
+// team policy change insights. Gated behind the sync-team-policies config
+// flag.
+type teamBuilder struct {
+	client *dropbox.Client
+}
+
+func newTeamBuilder(client *dropbox.Client) *teamBuilder {
+	return &teamBuilder{client: client}
+}
+
+func (b *teamBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return teamResourceType
+}
+
+func (b *teamBuilder) List(ctx context.Context, _ *v2.ResourceId, _ resourceSdk.SyncOpAttrs) ([]*v2.Resource, *resourceSdk.SyncOpResults, error) {
+	var outAnnotations annotations.Annotations
+
+	team, rateLimitData, err := b.client.GetTeamInfo(ctx)
+	if err != nil {
+		outAnnotations.WithRateLimiting(rateLimitData)
+		return nil, &resourceSdk.SyncOpResults{
+			Annotations: outAnnotations,
+		}, fmt.Errorf("dropbox-connector: failed to get team info: %w", err)
+	}
+
+	values, rateLimitData, err := b.client.GetFeatureValues(ctx, teamFeatures)
+	outAnnotations.WithRateLimiting(rateLimitData)
+	if err != nil {
+		return nil, &resourceSdk.SyncOpResults{
+			Annotations: outAnnotations,
+		}, fmt.Errorf("dropbox-connector: failed to get team feature values: %w", err)
+	}
+
+	profile := map[string]interface{}{
+		"team_id":               team.TeamID,
+		"name":                  team.Name,
+		"num_licensed_users":    team.NumLicensedUsers,
+		"num_provisioned_users": team.NumProvisionedUsers,
+		"num_used_licenses":     team.NumUsedLicenses,
+	}
+	for _, value := range values {
+		if value.Feature != "" && value.Value != nil {
+			profile[value.Feature] = value.Value
+		}
+	}
+
+	resource, err := resourceSdk.NewResource(
+		team.Name,
+		teamResourceType,
+		team.TeamID,
+		resourceSdk.WithResourceProfile(profile),
+	)
+	if err != nil {
+		return nil, &resourceSdk.SyncOpResults{
+			Annotations: outAnnotations,
+		}, err
+	}
+
+	return []*v2.Resource{resource}, &resourceSdk.SyncOpResults{
+		Annotations: outAnnotations,
+	}, nil
+}
+
+func (b *teamBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ resourceSdk.SyncOpAttrs) ([]*v2.Entitlement, *resourceSdk.SyncOpResults, error) {
+	return nil, nil, nil
+}
+
+func (b *teamBuilder) Grants(_ context.Context, _ *v2.Resource, _ resourceSdk.SyncOpAttrs) ([]*v2.Grant, *resourceSdk.SyncOpResults, error) {
+	return nil, nil, nil
+}
+
+// teamPolicyChangeBuilder syncs a security insight on the team for each
+// policy change in the team_policies category of the team event log over the
+// insight window, recording who changed which policy from which value to
+// which. Gated behind the sync-team-policies config flag; requires the
+// events.read and team_info.read scopes.
+type teamPolicyChangeBuilder struct {
+	client *dropbox.Client
+	window time.Duration
+}
+
+func newTeamPolicyChangeBuilder(client *dropbox.Client, window time.Duration) *teamPolicyChangeBuilder {
+	if window <= 0 {
+		window = defaultTeamPolicyInsightWindow
+	}
+	return &teamPolicyChangeBuilder{client: client, window: window}
+}
+
+func (b *teamPolicyChangeBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return teamPolicyChangeResourceType
+}
+
+// List reads the whole insight window before returning; the team_policies
+// category only records admin changes, so it is low-volume.
+// teamPolicyChangePageToken is the page token of teamPolicyChangeBuilder.List:
+// the team's ID, read on the first page, and the Dropbox cursor of the next
+// page of the team_policies category.
+type teamPolicyChangePageToken struct {
+	TeamID string `json:"team_id"`
+	Cursor string `json:"cursor"`
+}
+
+func unmarshalTeamPolicyChangePageToken(token string) (*teamPolicyChangePageToken, error) {
+	pt := &teamPolicyChangePageToken{}
+	if token == "" {
+		return pt, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, pt); err != nil {
+		return nil, err
+	}
+	return pt, nil
+}
+
+func (pt *teamPolicyChangePageToken) marshal() (string, error) {
+	data, err := json.Marshal(pt)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// List reads a page of the team_policies category per call, from the start
+// of the insight window, and returns an insight for each of its events.
+func (b *teamPolicyChangeBuilder) List(ctx context.Context, _ *v2.ResourceId, attr resourceSdk.SyncOpAttrs) ([]*v2.Resource, *resourceSdk.SyncOpResults, error) {
+	var outAnnotations annotations.Annotations
+
+	pt, err := unmarshalTeamPolicyChangePageToken(attr.PageToken.Token)
+	if err != nil {
+		return nil, &resourceSdk.SyncOpResults{
+			Annotations: outAnnotations,
+		}, fmt.Errorf("dropbox-connector: failed to unmarshal team policy page token: %w", err)
+	}
+
+	var payload *dropbox.GetTeamEventsPayload
+	var rateLimitData *v2.RateLimitDescription
+	if pt.Cursor == "" {
+		var team *dropbox.TeamInfoPayload
+		team, rateLimitData, err = b.client.GetTeamInfo(ctx)
+		outAnnotations.WithRateLimiting(rateLimitData)
+		if err != nil {
+			return nil, &resourceSdk.SyncOpResults{
+				Annotations: outAnnotations,
+			}, fmt.Errorf("dropbox-connector: failed to get team info: %w", err)
+		}
+		pt.TeamID = team.TeamID
+
+		start := time.Now().Add(-b.window)
+		payload, rateLimitData, err = b.client.GetTeamEvents(ctx, teamPoliciesEventCategory, &start, 0)
+	} else {
+		payload, rateLimitData, err = b.client.GetTeamEventsContinue(ctx, pt.Cursor)
+	}
+	outAnnotations.WithRateLimiting(rateLimitData)
+	if err != nil {
+		return nil, &resourceSdk.SyncOpResults{
+			Annotations: outAnnotations,
+		}, fmt.Errorf("dropbox-connector: failed to list team policy events: %w", err)
+	}
+
+	resources := make([]*v2.Resource, 0, len(payload.Events))
+	for i := range payload.Events {
+		resource, err := teamPolicyChangeResource(&payload.Events[i], pt.TeamID)
+		if err != nil {
+			return nil, &resourceSdk.SyncOpResults{
+				Annotations: outAnnotations,
+			}, err
+		}
+		resources = append(resources, resource)
+	}
+
+	if !payload.HasMore {
+		return resources, &resourceSdk.SyncOpResults{
+			Annotations: outAnnotations,
+		}, nil
+	}
+
+	pt.Cursor = payload.Cursor
+	nextToken, err := pt.marshal()
+	if err != nil {
+		return nil, &resourceSdk.SyncOpResults{
+			Annotations: outAnnotations,
+		}, fmt.Errorf("dropbox-connector: failed to marshal team policy page token: %w", err)
+	}
+	return resources, &resourceSdk.SyncOpResults{
+		NextPageToken: nextToken,
+		Annotations:   outAnnotations,
+	}, nil
+}
+
+func (b *teamPolicyChangeBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ resourceSdk.SyncOpAttrs) ([]*v2.Entitlement, *resourceSdk.SyncOpResults, error) {
+	return nil, nil, nil
+}
+
+func (b *teamPolicyChangeBuilder) Grants(_ context.Context, _ *v2.Resource, _ resourceSdk.SyncOpAttrs) ([]*v2.Grant, *resourceSdk.SyncOpResults, error) {
+	return nil, nil, nil
+}
+
+// teamPolicyChangeResource builds the insight for a team_policies event.
+// Events whose details don't record values, such as those adding or removing
+// list entries, still name the policy and who changed it.
+func teamPolicyChangeResource(e *dropbox.TeamEvent, teamID string) (*v2.Resource, error) {
+	policy := strings.ReplaceAll(e.EventType.Tag, "_", " ")
+
+	actor := teamPolicyActor(e.Actor)
+
+	profile := map[string]interface{}{
+		"event_type": e.EventType.Tag,
+		"changed_by": actor,
+		"changed_at": e.Timestamp,
+	}
+	if e.Origin != nil && e.Origin.GeoLocation != nil && e.Origin.GeoLocation.IPAddress != "" {
+		profile["ip_address"] = e.Origin.GeoLocation.IPAddress
+	}
+
+	issue := fmt.Sprintf("%s changed %s", actor, policy)
+	var details dropbox.PolicyChangeDetails
+	if err := e.DecodeDetails(&details); err == nil && details.NewValue != nil {
+		profile["new_value"] = string(*details.NewValue)
+		if details.PreviousValue != nil {
+			profile["previous_value"] = string(*details.PreviousValue)
+			issue = fmt.Sprintf("%s changed %s from %s to %s", actor, policy, *details.PreviousValue, *details.NewValue)
+		} else {
+			issue = fmt.Sprintf("%s set %s to %s", actor, policy, *details.NewValue)
+		}
+	}
+
+	severity := "Low"
+	for _, s := range teamPolicySeverities {
+		if strings.HasPrefix(e.EventType.Tag, s.prefix) {
+			severity = s.severity
+			break
+		}
+	}
+
+	insightOptions := []resourceSdk.SecurityInsightTraitOption{
+		resourceSdk.WithIssue(issue),
+		resourceSdk.WithIssueSeverity(severity),
+		resourceSdk.WithInsightResourceTarget(&v2.ResourceId{ResourceType: teamResourceType.Id, Resource: teamID}),
+	}
+	if occurredAt, err := time.Parse(dropbox.TimestampFormat, e.Timestamp); err == nil {
+		insightOptions = append(insightOptions, resourceSdk.WithInsightObservedAt(occurredAt))
+	}
+
+	return resourceSdk.NewResource(
+		issue,
+		teamPolicyChangeResourceType,
+		e.ID(),
+		resourceSdk.WithDescription(issue),
+		resourceSdk.WithResourceProfile(profile),
+		resourceSdk.WithSecurityInsightTrait(insightOptions...),
+	)
+}
+
+// teamPolicyActor names who changed a policy.
+func teamPolicyActor(actor dropbox.ActorLogInfo) string {
+	if user := actor.UserInfo(); user != nil {
+		if user.Email != "" {
+			return user.Email
+		}
+		if user.DisplayName != "" {
+			return user.DisplayName
+		}
+	}
+	switch {
+	case actor.App != nil && actor.App.DisplayName != "":
+		return actor.App.DisplayName
+	case actor.ResellerName != "":
+		return actor.ResellerName
+	case actor.Tag == "anonymous":
+		return "An anonymous user"
+	default:
+		return "Dropbox"
+	}
+}
@@ -0,0 +1,132 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-dropbox/pkg/connector/dropbox"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	resourceSdk "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/stretchr/testify/require"
+)
+
+func newTeamPoliciesServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/2/team/get_info":
+			_, _ = w.Write([]byte(`{"name": "Acme", "team_id": "dbtid:1", "num_licensed_users": 10, "num_provisioned_users": 8, "num_used_licenses": 8}`))
+		case "/2/team/features/get_values":
+			var body dropbox.GetFeatureValuesBody
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			require.Len(t, body.Features, len(teamFeatures))
+			_, _ = w.Write([]byte(`{"values": [
+				{".tag": "upload_api_rate_limit", "upload_api_rate_limit": {".tag": "limit", "limit": 25000}},
+				{".tag": "has_team_shared_dropbox", "has_team_shared_dropbox": {".tag": "has_team_shared_dropbox", "has_team_shared_dropbox": true}},
+				{".tag": "has_distinct_member_homes", "has_distinct_member_homes": {".tag": "other"}}
+			]}`))
+		case "/2/team_log/get_events":
+			_, _ = w.Write([]byte(`{"events": [
+				{"timestamp": "2024-03-01T10:00:00Z", "event_category": {".tag": "team_policies"}, "event_type": {".tag": "sso_change_policy"},
+				 "actor": {".tag": "admin", "admin": {".tag": "team_member", "team_member_id": "dbmid:1", "email": "admin@example.com"}},
+				 "origin": {"geo_location": {"ip_address": "192.0.2.1"}, "access_method": {".tag": "admin_console"}},
+				 "details": {".tag": "sso_change_policy_details", "new_value": {".tag": "optional"}, "previous_value": {".tag": "required"}}},
+				{"timestamp": "2024-03-02T10:00:00Z", "event_category": {".tag": "team_policies"}, "event_type": {".tag": "device_approvals_change_desktop_policy"},
+				 "actor": {".tag": "dropbox"},
+				 "details": {".tag": "device_approvals_change_desktop_policy_details", "new_value": {".tag": "limited", "limit": 3}}},
+				{"timestamp": "2024-03-03T10:00:00Z", "event_category": {".tag": "team_policies"}, "event_type": {".tag": "allow_download_enabled"},
+				 "actor": {".tag": "admin", "admin": {".tag": "team_member", "team_member_id": "dbmid:1", "email": "admin@example.com"}},
+				 "details": {".tag": "allow_download_enabled_details"}}
+			], "cursor": "cursor-1", "has_more": true}`))
+		case "/2/team_log/get_events/continue":
+			var body dropbox.GetTeamEventsContinueBody
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			require.Equal(t, "cursor-1", body.Cursor)
+			_, _ = w.Write([]byte(`{"events": [
+				{"timestamp": "2024-03-04T10:00:00Z", "event_category": {".tag": "team_policies"}, "event_type": {".tag": "tfa_change_policy"},
+				 "actor": {".tag": "admin", "admin": {".tag": "team_member", "team_member_id": "dbmid:1", "email": "admin@example.com"}},
+				 "details": {".tag": "tfa_change_policy_details", "new_value": {".tag": "allow_disable"}, "previous_value": {".tag": "required"}}}
+			], "cursor": "cursor-2", "has_more": false}`))
+		default:
+			t.Fatalf("unexpected path %q", r.URL.Path)
+		}
+	}))
+}
+
+func TestTeamBuilder_List_RecordsFeatureValues(t *testing.T) {
+	server := newTeamPoliciesServer(t)
+	defer server.Close()
+
+	resources, _, err := newTeamBuilder(newTestConnector(t, server).client).List(context.Background(), nil, resourceSdk.SyncOpAttrs{})
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+
+	team := resources[0]
+	require.Equal(t, "dbtid:1", team.GetId().GetResource())
+	require.Equal(t, "Acme", team.GetDisplayName())
+
+	profile := team.GetProfile().AsMap()
+	require.Equal(t, float64(25000), profile["upload_api_rate_limit"])
+	require.Equal(t, true, profile["has_team_shared_dropbox"])
+	require.Equal(t, "other", profile["has_distinct_member_homes"])
+	require.Equal(t, float64(8), profile["num_used_licenses"])
+}
+
+func TestTeamPolicyChangeBuilder_List(t *testing.T) {
+	server := newTeamPoliciesServer(t)
+	defer server.Close()
+
+	builder := newTeamPolicyChangeBuilder(newTestConnector(t, server).client, 7*24*time.Hour)
+	resources, results, err := builder.List(context.Background(), nil, resourceSdk.SyncOpAttrs{})
+	require.NoError(t, err)
+	require.Len(t, resources, 3)
+	require.NotEmpty(t, results.NextPageToken)
+
+	var issues, severities []string
+	for _, resource := range resources {
+		trait, err := resourceSdk.GetSecurityInsightTrait(resource)
+		require.NoError(t, err)
+		require.Equal(t, "team", trait.GetResourceId().GetResourceType())
+		require.Equal(t, "dbtid:1", trait.GetResourceId().GetResource())
+		issues = append(issues, trait.GetIssue().GetValue())
+		severities = append(severities, trait.GetIssue().GetSeverity())
+	}
+	require.Equal(t, []string{
+		"admin@example.com changed sso change policy from required to optional",
+		`Dropbox set device approvals change desktop policy to limited {"limit":3}`,
+		"admin@example.com changed allow download enabled",
+	}, issues)
+	require.Equal(t, []string{"High", "Medium", "Low"}, severities)
+
+	profile := resources[0].GetProfile().AsMap()
+	require.Equal(t, "required", profile["previous_value"])
+	require.Equal(t, "optional", profile["new_value"])
+	require.Equal(t, "admin@example.com", profile["changed_by"])
+	require.Equal(t, "192.0.2.1", profile["ip_address"])
+
+	// The next page continues the cursor and still names the team.
+	resources, results, err = builder.List(context.Background(), nil, resourceSdk.SyncOpAttrs{PageToken: pagination.Token{Token: results.NextPageToken}})
+	require.NoError(t, err)
+	require.Empty(t, results.NextPageToken)
+	require.Len(t, resources, 1)
+	trait, err := resourceSdk.GetSecurityInsightTrait(resources[0])
+	require.NoError(t, err)
+	require.Equal(t, "dbtid:1", trait.GetResourceId().GetResource())
+	require.Equal(t, "admin@example.com changed tfa change policy from required to allow_disable", trait.GetIssue().GetValue())
+}
+
+func TestConnector_RequiredScopes_TeamPoliciesNeedTeamInfoRead(t *testing.T) {
+	c := &Connector{}
+	require.NotContains(t, c.requiredScopes(), "team_info.read")
+
+	c.syncTeamPolicies = true
+	require.Contains(t, c.requiredScopes(), "team_info.read")
+	require.Contains(t, c.requiredScopes(), "events.read")
+	require.Contains(t, requiredScopes(teamPolicyChangeResourceType), "team_info.read")
+}
@@ -0,0 +1,205 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conductorone/baton-dropbox/pkg/connector/dropbox"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/session"
+	"github.com/conductorone/baton-sdk/pkg/types/sessions"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	tfaEventCategory = "tfa"
+
+	// twoStepSessionPrefix namespaces members' two-step verification status
+	// in the session store, keyed by team member ID.
+	twoStepSessionPrefix = "two_step_verification"
+	// twoStepLogReadKey marks that the tfa category was read this sync. Team
+	// member IDs start with "dbmid:", so it can't collide with one.
+	twoStepLogReadKey = "log_read"
+)
+
+// Two-step verification statuses reported on user profiles. A member with no
+// tfa events in the retained event log is reported as unknown.
+const (
+	twoStepEnabled  = "enabled"
+	twoStepDisabled = "disabled"
+	twoStepUnknown  = "unknown"
+)
+
+// twoStepStatus is a member's two-step verification posture, replayed from
+// the tfa category of the team event log. Dropbox has no 2FA field on team
+// members, and connectors have nowhere to keep state between syncs, so the
+// posture is derived afresh on every sync from the events Dropbox retains.
+type twoStepStatus struct {
+	Status string `json:"status,omitempty"`
+	// Method is "sms" or "authenticator", when the log says which.
+	Method       string `json:"method,omitempty"`
+	SecurityKeys int    `json:"security_keys,omitempty"`
+	BackupPhone  bool   `json:"backup_phone,omitempty"`
+	// Exempt is set while the member has an exception from the team's 2FA
+	// requirement.
+	Exempt    bool   `json:"exempt,omitempty"`
+	ChangedAt string `json:"changed_at,omitempty"`
+}
+
+// twoStepStatuses replays tfa events, oldest first, into each member's
+// current posture.
+func twoStepStatuses(ctx context.Context, events []dropbox.TeamEvent) map[string]twoStepStatus {
+	l := ctxzap.Extract(ctx)
+
+	statuses := map[string]twoStepStatus{}
+	for i := range events {
+		e := &events[i]
+		member := e.Context.TeamMember()
+		if member == nil {
+			member = e.Actor.UserInfo()
+		}
+		if member == nil || member.TeamMemberID == "" {
+			continue
+		}
+
+		status := statuses[member.TeamMemberID]
+		switch e.EventType.Tag {
+		case "tfa_change_status":
+			var details dropbox.TfaChangeStatusDetails
+			if err := e.DecodeDetails(&details); err != nil {
+				l.Debug("dropbox-connector: skipping unparseable tfa event", zap.String("timestamp", e.Timestamp), zap.Error(err))
+				continue
+			}
+			switch details.NewValue.Tag {
+			case twoStepDisabled:
+				status.Status, status.Method = twoStepDisabled, ""
+			case "sms", "authenticator":
+				status.Status, status.Method = twoStepEnabled, details.NewValue.Tag
+			default:
+				status.Status = twoStepEnabled
+			}
+		case "tfa_reset":
+			// An admin reset removes every second factor.
+			status = twoStepStatus{Status: twoStepDisabled, Exempt: status.Exempt}
+		case "tfa_add_security_key":
+			status.SecurityKeys++
+		case "tfa_remove_security_key":
+			status.SecurityKeys = max(status.SecurityKeys-1, 0)
+		case "tfa_add_backup_phone", "tfa_change_backup_phone":
+			status.BackupPhone = true
+		case "tfa_remove_backup_phone":
+			status.BackupPhone = false
+		case "tfa_add_exception":
+			status.Exempt = true
+		case "tfa_remove_exception":
+			status.Exempt = false
+		default:
+			continue
+		}
+		status.ChangedAt = e.Timestamp
+		statuses[member.TeamMemberID] = status
+	}
+	return statuses
+}
+
+// readTwoStepStatuses reads the whole retained tfa category of the team event
+// log. The category is low-volume, so it is read in one go.
+func readTwoStepStatuses(ctx context.Context, client *dropbox.Client) (map[string]twoStepStatus, *v2.RateLimitDescription, error) {
+	payload, rateLimitData, err := client.GetTeamEvents(ctx, tfaEventCategory, nil, 0)
+
+	var events []dropbox.TeamEvent
+	for err == nil {
+		events = append(events, payload.Events...)
+		if !payload.HasMore {
+			break
+		}
+		payload, rateLimitData, err = client.GetTeamEventsContinue(ctx, payload.Cursor)
+	}
+	if err != nil {
+		return nil, rateLimitData, fmt.Errorf("dropbox-connector: failed to list two-step verification events: %w", err)
+	}
+	return twoStepStatuses(ctx, events), rateLimitData, nil
+}
+
+// loadTwoStepStatuses returns the posture of memberIDs. The tfa category is
+// read on the first call of a sync and kept in the session store for the
+// pages after it. The SDK scopes the store to one sync and clears it when the
+// sync ends, so neither the posture nor an event log cursor survives to the
+// next sync: each sync replays the whole retained category rather than only
+// the events since the last one. Without a session store the log is read
+// every time.
+func loadTwoStepStatuses(
+	ctx context.Context,
+	client *dropbox.Client,
+	ss sessions.SessionStore,
+	memberIDs []string,
+) (map[string]twoStepStatus, *v2.RateLimitDescription, error) {
+	if ss == nil {
+		return readTwoStepStatuses(ctx, client)
+	}
+
+	prefix := sessions.WithPrefix(twoStepSessionPrefix)
+	_, read, err := ss.Get(ctx, twoStepLogReadKey, prefix)
+	if err != nil {
+		return nil, nil, fmt.Errorf("dropbox-connector: failed to read two-step verification status from session: %w", err)
+	}
+	if !read {
+		statuses, rateLimitData, err := readTwoStepStatuses(ctx, client)
+		if err != nil {
+			return nil, rateLimitData, err
+		}
+		if err := session.SetManyJSON(ctx, ss, statuses, prefix); err != nil {
+			return nil, rateLimitData, fmt.Errorf("dropbox-connector: failed to store two-step verification status in session: %w", err)
+		}
+		if err := ss.Set(ctx, twoStepLogReadKey, []byte("true"), prefix); err != nil {
+			return nil, rateLimitData, fmt.Errorf("dropbox-connector: failed to store two-step verification status in session: %w", err)
+		}
+		return statuses, rateLimitData, nil
+	}
+
+	statuses, err := session.GetManyJSON[twoStepStatus](ctx, ss, memberIDs, prefix)
+	if err != nil {
+		return nil, nil, fmt.Errorf("dropbox-connector: failed to read two-step verification status from session: %w", err)
+	}
+	return statuses, nil, nil
+}
+
+// setTwoStepProfile adds a member's two-step verification posture to the
+// user resource's profile.
+func setTwoStepProfile(resource *v2.Resource, status twoStepStatus) {
+	fields := map[string]*structpb.Value{
+		"two_step_verification": structpb.NewStringValue(twoStepUnknown),
+	}
+	if status.Status != "" {
+		fields["two_step_verification"] = structpb.NewStringValue(status.Status)
+	}
+	if status.Method != "" {
+		fields["two_step_verification_method"] = structpb.NewStringValue(status.Method)
+	}
+	if status.SecurityKeys > 0 {
+		fields["two_step_verification_security_keys"] = structpb.NewNumberValue(float64(status.SecurityKeys))
+	}
+	if status.BackupPhone {
+		fields["two_step_verification_backup_phone"] = structpb.NewBoolValue(true)
+	}
+	if status.Exempt {
+		fields["two_step_verification_exempt"] = structpb.NewBoolValue(true)
+	}
+	if status.ChangedAt != "" {
+		fields["two_step_verification_changed_at"] = structpb.NewStringValue(status.ChangedAt)
+	}
+
+	profile := resource.GetProfile()
+	if profile == nil {
+		profile = &structpb.Struct{}
+		resource.SetProfile(profile)
+	}
+	if profile.Fields == nil {
+		profile.Fields = map[string]*structpb.Value{}
+	}
+	for key, value := range fields {
+		profile.Fields[key] = value
+	}
+}
@@ -0,0 +1,140 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	resourceSdk "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/conductorone/baton-sdk/pkg/types/sessions"
+	"github.com/stretchr/testify/require"
+)
+
+// memorySessionStore is a map-backed sessions.SessionStore honoring prefixes.
+type memorySessionStore map[string][]byte
+
+var _ sessions.SessionStore = memorySessionStore{}
+
+func (m memorySessionStore) key(ctx context.Context, key string, opt []sessions.SessionStoreOption) string {
+	bag := &sessions.SessionStoreBag{}
+	for _, o := range opt {
+		_ = o(ctx, bag)
+	}
+	return bag.Prefix + "/" + key
+}
+
+func (m memorySessionStore) Get(ctx context.Context, key string, opt ...sessions.SessionStoreOption) ([]byte, bool, error) {
+	value, ok := m[m.key(ctx, key, opt)]
+	return value, ok, nil
+}
+
+func (m memorySessionStore) GetMany(ctx context.Context, keys []string, opt ...sessions.SessionStoreOption) (map[string][]byte, []string, error) {
+	values := map[string][]byte{}
+	for _, key := range keys {
+		if value, ok := m[m.key(ctx, key, opt)]; ok {
+			values[key] = value
+		}
+	}
+	return values, nil, nil
+}
+
+func (m memorySessionStore) Set(ctx context.Context, key string, value []byte, opt ...sessions.SessionStoreOption) error {
+	m[m.key(ctx, key, opt)] = value
+	return nil
+}
+
+func (m memorySessionStore) SetMany(ctx context.Context, values map[string][]byte, opt ...sessions.SessionStoreOption) error {
+	for key, value := range values {
+		m[m.key(ctx, key, opt)] = value
+	}
+	return nil
+}
+
+func (m memorySessionStore) Delete(ctx context.Context, key string, opt ...sessions.SessionStoreOption) error {
+	delete(m, m.key(ctx, key, opt))
+	return nil
+}
+
+func (m memorySessionStore) Clear(_ context.Context, _ ...sessions.SessionStoreOption) error {
+	clear(m)
+	return nil
+}
+
+func (m memorySessionStore) GetAll(_ context.Context, _ string, _ ...sessions.SessionStoreOption) (map[string][]byte, string, error) {
+	return map[string][]byte(m), "", nil
+}
+
+func TestUserBuilder_List_AddsTwoStepVerificationStatus(t *testing.T) {
+	member := func(id string) string {
+		return fmt.Sprintf(`{"profile": {"team_member_id": %q, "email": "%s@example.com", "status": {".tag": "active"}, "membership_type": {".tag": "full"}}}`, id, id)
+	}
+	tfaEvent := func(timestamp, eventType, teamMemberID, details string) string {
+		return fmt.Sprintf(`{"timestamp": %q, "event_category": {".tag": "tfa"}, "event_type": {".tag": %q},
+			"actor": {".tag": "user", "user": {".tag": "team_member", "team_member_id": %q}},
+			"context": {".tag": "team_member", "team_member_id": %q},
+			"details": {".tag": "%s_details"%s}}`, timestamp, eventType, teamMemberID, teamMemberID, eventType, details)
+	}
+	events := []string{
+		tfaEvent("2024-01-01T00:00:00Z", "tfa_change_status", "dbmid:1", `, "new_value": {".tag": "authenticator"}`),
+		tfaEvent("2024-01-02T00:00:00Z", "tfa_add_security_key", "dbmid:1", ""),
+		tfaEvent("2024-01-03T00:00:00Z", "tfa_change_status", "dbmid:2", `, "new_value": {".tag": "sms"}`),
+		tfaEvent("2024-01-04T00:00:00Z", "tfa_add_exception", "dbmid:2", ""),
+		tfaEvent("2024-01-05T00:00:00Z", "tfa_reset", "dbmid:2", ""),
+	}
+
+	logReads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/2/team_log/get_events":
+			logReads++
+			_, _ = fmt.Fprintf(w, `{"events": [%s], "cursor": "log-cursor", "has_more": false}`, strings.Join(events, ","))
+		case "/2/team/members/list_v2":
+			_, _ = fmt.Fprintf(w, `{"members": [%s, %s], "cursor": "cursor-1", "has_more": true}`, member("dbmid:1"), member("dbmid:2"))
+		case "/2/team/members/list/continue_v2":
+			_, _ = fmt.Fprintf(w, `{"members": [%s], "cursor": "cursor-2", "has_more": false}`, member("dbmid:3"))
+		default:
+			t.Fatalf("unexpected path %q", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	builder := newUserBuilder(newTestConnector(t, server).client, false, 0, true)
+	store := memorySessionStore{}
+
+	profiles := map[string]map[string]interface{}{}
+	token := ""
+	for page := 0; page < 5; page++ {
+		resources, results, err := builder.List(context.Background(), nil, resourceSdk.SyncOpAttrs{
+			PageToken: pagination.Token{Token: token},
+			Session:   store,
+		})
+		require.NoError(t, err)
+		for _, resource := range resources {
+			profiles[resource.GetId().GetResource()] = resource.GetProfile().AsMap()
+		}
+		token = results.NextPageToken
+		if token == "" {
+			break
+		}
+	}
+
+	// The log is read once and later pages are served from the session store.
+	require.Equal(t, 1, logReads)
+
+	require.Equal(t, "enabled", profiles["dbmid:1"]["two_step_verification"])
+	require.Equal(t, "authenticator", profiles["dbmid:1"]["two_step_verification_method"])
+	require.Equal(t, float64(1), profiles["dbmid:1"]["two_step_verification_security_keys"])
+
+	require.Equal(t, "disabled", profiles["dbmid:2"]["two_step_verification"])
+	require.NotContains(t, profiles["dbmid:2"], "two_step_verification_method")
+	require.Equal(t, true, profiles["dbmid:2"]["two_step_verification_exempt"])
+	require.Equal(t, "2024-01-05T00:00:00Z", profiles["dbmid:2"]["two_step_verification_changed_at"])
+
+	require.Equal(t, "unknown", profiles["dbmid:3"]["two_step_verification"])
+	require.Equal(t, "active", profiles["dbmid:3"]["status"])
+}
@@ -3,7 +3,7 @@ package connector
 import (
 	"context"
 	"fmt"
-	"strings"
+	"time"
 
 	"github.com/conductorone/baton-dropbox/pkg/connector/dropbox"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -13,6 +13,8 @@ import (
 	resourceSdk "github.com/conductorone/baton-sdk/pkg/types/resource"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -25,15 +27,26 @@ type userBuilder struct {
 	// so ResourceType annotates this type to tell the SDK to skip calling
 	// Grants at all in that case.
 	syncLicenses bool
+	// staleInviteAge is how long an invitation may stay pending before the
+	// member is flagged as stale (see invitationIsStale). Zero disables it.
+	staleInviteAge time.Duration
+	// syncTwoStepVerification adds each member's two-step verification
+	// posture, replayed from the team event log, to their profile.
+	syncTwoStepVerification bool
 }
 
-// mapUserStatus converts Dropbox user status to SDK status.
+// invitationStaleStatus is the resource status detail reported, in place of
+// "invited", for invited members whose invitation is older than the
+// configured stale-invite age.
+const invitationStaleStatus = "invitation_stale"
+
+// mapUserStatus converts Dropbox user status to SDK status. An invited member
+// can't use Dropbox until they accept, yet hasn't been disabled either, so
+// invitations map to unspecified; the status details say "invited".
 func mapUserStatus(status dropbox.Tag) v2.UserTrait_Status_Status {
 	switch status.Tag {
 	case "active":
 		return v2.UserTrait_Status_STATUS_ENABLED
-	case "invited":
-		return v2.UserTrait_Status_STATUS_ENABLED
 	case "suspended":
 		return v2.UserTrait_Status_STATUS_DISABLED
 	case "removed":
@@ -43,7 +56,23 @@ func mapUserStatus(status dropbox.Tag) v2.UserTrait_Status_Status {
 	}
 }
 
-func userResource(user dropbox.Profile, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+// invitationIsStale reports whether a member's invitation has been pending for
+// longer than maxAge. Members that aren't invited, or whose invited_on
+// timestamp is missing or unparseable, are never stale.
+func invitationIsStale(user dropbox.Profile, maxAge time.Duration, now time.Time) bool {
+	if maxAge <= 0 || user.Status.Tag != "invited" || user.InvitedOn == "" {
+		return false
+	}
+
+	invitedOn, err := time.Parse(dropbox.TimestampFormat, user.InvitedOn)
+	if err != nil {
+		return false
+	}
+
+	return now.Sub(invitedOn) > maxAge
+}
+
+func userResource(user dropbox.Profile, staleInviteAge time.Duration, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
 	profile := map[string]interface{}{
 		"id":              user.AccountID,
 		"email":           user.Email,
@@ -55,18 +84,83 @@ func userResource(user dropbox.Profile, parentResourceID *v2.ResourceId) (*v2.Re
 	}
 
 	userStatus := mapUserStatus(user.Status)
+	statusDetails := user.Status.Tag
+
+	if user.InvitedOn != "" {
+		profile["invited_on"] = user.InvitedOn
+	}
+	if user.JoinedOn != "" {
+		profile["joined_on"] = user.JoinedOn
+	}
+	if user.SuspendedOn != "" {
+		profile["suspended_on"] = user.SuspendedOn
+	}
+	if user.ExternalID != "" {
+		profile["external_id"] = user.ExternalID
+	}
+	if user.PersistentID != "" {
+		profile["persistent_id"] = user.PersistentID
+	}
+	if user.AccountType.Tag != "" {
+		profile["account_type"] = user.AccountType.Tag
+	}
+	profile["is_directory_restricted"] = user.IsDirectoryRestricted
+	// A stale invitation keeps the invited status; only the details and the
+	// profile flag mark it as gone unanswered past the configured age.
+	if invitationIsStale(user, staleInviteAge, time.Now()) {
+		statusDetails = invitationStaleStatus
+		profile["invitation_stale"] = true
+	}
 
 	userTraitOptions := []resourceSdk.UserTraitOption{
 		resourceSdk.WithEmail(user.Email, true),
 		resourceSdk.WithUserLogin(user.Email),
 	}
 
+	// The external ID is set by admins or a directory sync (commonly to the
+	// HRIS employee number) and the persistent ID is the SSO identifier; both
+	// are stable keys for matching the member to an employee record.
+	var employeeIDs []string
+	for _, id := range []string{user.ExternalID, user.PersistentID} {
+		if id != "" {
+			employeeIDs = append(employeeIDs, id)
+		}
+	}
+	if len(employeeIDs) > 0 {
+		userTraitOptions = append(userTraitOptions, resourceSdk.WithEmployeeID(employeeIDs...))
+	}
+
+	// The photo itself is served by Connector.Asset, keyed by team member ID.
+	if user.ProfilePhotoURL != "" {
+		userTraitOptions = append(userTraitOptions, resourceSdk.WithUserIcon(&v2.AssetRef{Id: user.TeamMemberID}))
+	}
+
+	if user.JoinedOn != "" {
+		if joinedOn, err := time.Parse(dropbox.TimestampFormat, user.JoinedOn); err == nil {
+			userTraitOptions = append(userTraitOptions, resourceSdk.WithCreatedAt(joinedOn))
+		}
+	}
+
+	// Only verified secondary emails are added to the trait: an unverified
+	// alias hasn't been proven to belong to the member, so it must not be
+	// used to match them to an identity. All aliases still go on the profile.
+	if len(user.SecondaryEmails) > 0 {
+		secondaryEmails := make([]interface{}, 0, len(user.SecondaryEmails))
+		for _, secondary := range user.SecondaryEmails {
+			secondaryEmails = append(secondaryEmails, secondary.Email)
+			if secondary.IsVerified {
+				userTraitOptions = append(userTraitOptions, resourceSdk.WithEmail(secondary.Email, false))
+			}
+		}
+		profile["secondary_emails"] = secondaryEmails
+	}
+
 	return resourceSdk.NewUserResource(
 		user.Email,
 		userResourceType,
 		user.TeamMemberID,
 		userTraitOptions,
-		resourceSdk.WithResourceStatus(v2.Status_ResourceStatus(userStatus), user.Status.Tag),
+		resourceSdk.WithResourceStatus(v2.Status_ResourceStatus(userStatus), statusDetails),
 		resourceSdk.WithResourceProfile(profile),
 		resourceSdk.WithParentResourceID(parentResourceID),
 	)
@@ -97,15 +191,7 @@ func (o *userBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId,
 	logger.Debug("Starting Users List", zap.String("token", attr.PageToken.Token))
 
 	outResources := []*v2.Resource{}
-	var payload *dropbox.ListUsersPayload
-	var rateLimitData *v2.RateLimitDescription
-	var err error
-
-	if token == "" {
-		payload, rateLimitData, err = o.ListUsers(ctx, limit)
-	} else {
-		payload, rateLimitData, err = o.ListUsersContinue(ctx, token)
-	}
+	members, nextToken, rateLimitData, err := nextMembersPage(ctx, o.Client, newListingSession(attr.Session, "users"), 0, token)
 
 	var outAnnotations annotations.Annotations
 	outAnnotations.WithRateLimiting(rateLimitData)
@@ -116,23 +202,37 @@ func (o *userBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId,
 		}, fmt.Errorf("error listing users: %w", err)
 	}
 
-	for _, user := range payload.Members {
-		resource, err := userResource(user.Profile, parentResourceID)
+	var twoStep map[string]twoStepStatus
+	if o.syncTwoStepVerification {
+		memberIDs := make([]string, 0, len(members))
+		for _, user := range members {
+			memberIDs = append(memberIDs, user.Profile.TeamMemberID)
+		}
+		var tfaRateLimitData *v2.RateLimitDescription
+		twoStep, tfaRateLimitData, err = loadTwoStepStatuses(ctx, o.Client, attr.Session, memberIDs)
 		if err != nil {
+			outAnnotations.WithRateLimiting(tfaRateLimitData)
 			return nil, &resourceSdk.SyncOpResults{
 				Annotations: outAnnotations,
 			}, err
 		}
-		outResources = append(outResources, resource)
 	}
 
-	var cursor string
-	if payload.HasMore {
-		cursor = payload.Cursor
+	for _, user := range members {
+		resource, err := userResource(user.Profile, o.staleInviteAge, parentResourceID)
+		if err != nil {
+			return nil, &resourceSdk.SyncOpResults{
+				Annotations: outAnnotations,
+			}, err
+		}
+		if o.syncTwoStepVerification {
+			setTwoStepProfile(resource, twoStep[user.Profile.TeamMemberID])
+		}
+		outResources = append(outResources, resource)
 	}
 
 	return outResources, &resourceSdk.SyncOpResults{
-		NextPageToken: cursor,
+		NextPageToken: nextToken,
 		Annotations:   outAnnotations,
 	}, nil
 }
@@ -173,10 +273,12 @@ func (o *userBuilder) Grants(ctx context.Context, resource *v2.Resource, _ resou
 	}, nil, nil
 }
 
-func newUserBuilder(client *dropbox.Client, syncLicenses bool) *userBuilder {
+func newUserBuilder(client *dropbox.Client, syncLicenses bool, staleInviteAge time.Duration, syncTwoStepVerification bool) *userBuilder {
 	return &userBuilder{
-		Client:       client,
-		syncLicenses: syncLicenses,
+		Client:                  client,
+		syncLicenses:            syncLicenses,
+		staleInviteAge:          staleInviteAge,
+		syncTwoStepVerification: syncTwoStepVerification,
 	}
 }
 
@@ -223,7 +325,7 @@ func (o *userBuilder) CreateAccount(
 	}
 
 	newUserProfile := response.Complete[0].Profile
-	newUserResource, err := userResource(newUserProfile, nil)
+	newUserResource, err := userResource(newUserProfile, o.staleInviteAge, nil)
 	if err != nil {
 		l.Error("error converting created user to resource", zap.Error(err))
 		return nil, nil, annos, err
@@ -249,7 +351,7 @@ func (o *userBuilder) Delete(ctx context.Context, resourceId *v2.ResourceId) (an
 	annos.WithRateLimiting(rateLimitData)
 
 	if err != nil {
-		if strings.Contains(err.Error(), "user_not_found") {
+		if status.Code(err) == codes.NotFound {
 			l.Info("user already deleted", zap.String("teamMemberID", teamMemberID))
 			return annos, nil
 		}
@@ -0,0 +1,285 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-dropbox/pkg/connector/dropbox"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	resourceSdk "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestUserResource_SecondaryEmails verifies that verified secondary emails are
+// added to the user trait as non-primary emails (so aliases can be matched to
+// an identity), while unverified ones only appear on the profile.
+func TestUserResource_SecondaryEmails(t *testing.T) {
+	res, err := userResource(dropbox.Profile{
+		AccountID:    "acc-1",
+		TeamMemberID: "dbmid:1",
+		Email:        "alice@example.com",
+		SecondaryEmails: []dropbox.SecondaryEmail{
+			{Email: "alice.alias@example.com", IsVerified: true},
+			{Email: "alice.pending@example.com", IsVerified: false},
+		},
+		Status:         dropbox.Tag{Tag: "active"},
+		MembershipType: dropbox.Tag{Tag: "full"},
+	}, 0, nil)
+	require.NoError(t, err)
+
+	trait, err := resourceSdk.GetUserTrait(res)
+	require.NoError(t, err)
+
+	emails := map[string]bool{}
+	for _, email := range trait.GetEmails() {
+		emails[email.GetAddress()] = email.GetIsPrimary()
+	}
+	require.Equal(t, map[string]bool{
+		"alice@example.com":       true,
+		"alice.alias@example.com": false,
+	}, emails)
+
+	secondary := res.GetProfile().AsMap()["secondary_emails"]
+	require.Equal(t, []interface{}{"alice.alias@example.com", "alice.pending@example.com"}, secondary)
+}
+
+func TestInvitationIsStale(t *testing.T) {
+	now := mustParseDropboxTime(t, "2024-03-01T00:00:00Z")
+	maxAge := 30 * 24 * time.Hour
+
+	cases := []struct {
+		name      string
+		status    string
+		invitedOn string
+		maxAge    time.Duration
+		wantStale bool
+	}{
+		{"old invitation", "invited", "2024-01-01T00:00:00Z", maxAge, true},
+		{"recent invitation", "invited", "2024-02-20T00:00:00Z", maxAge, false},
+		{"stale detection disabled", "invited", "2024-01-01T00:00:00Z", 0, false},
+		{"missing invited_on", "invited", "", maxAge, false},
+		{"unparseable invited_on", "invited", "yesterday", maxAge, false},
+		{"active member", "active", "2024-01-01T00:00:00Z", maxAge, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			profile := dropbox.Profile{Status: dropbox.Tag{Tag: tc.status}, InvitedOn: tc.invitedOn}
+			require.Equal(t, tc.wantStale, invitationIsStale(profile, tc.maxAge, now))
+		})
+	}
+}
+
+// TestUserResource_StaleInvitationIsFlagged verifies that invited members are
+// reported with an unspecified status rather than as enabled, that a stale
+// invitation is marked by its status details and profile flag only, and that
+// the invitation timestamp is carried on the profile.
+func TestUserResource_StaleInvitationIsFlagged(t *testing.T) {
+	res, err := userResource(dropbox.Profile{
+		TeamMemberID:   "dbmid:1",
+		Email:          "invitee@example.com",
+		Status:         dropbox.Tag{Tag: "invited"},
+		MembershipType: dropbox.Tag{Tag: "full"},
+		InvitedOn:      "2020-01-01T00:00:00Z",
+	}, 30*24*time.Hour, nil)
+	require.NoError(t, err)
+
+	require.Equal(t, v2.Status_ResourceStatus(v2.UserTrait_Status_STATUS_UNSPECIFIED), res.GetStatus().GetStatus())
+	require.Equal(t, invitationStaleStatus, res.GetStatus().GetDetails())
+
+	profile := res.GetProfile().AsMap()
+	require.Equal(t, "2020-01-01T00:00:00Z", profile["invited_on"])
+	require.Equal(t, true, profile["invitation_stale"])
+
+	res, err = userResource(dropbox.Profile{
+		TeamMemberID:   "dbmid:2",
+		Email:          "recent@example.com",
+		Status:         dropbox.Tag{Tag: "invited"},
+		MembershipType: dropbox.Tag{Tag: "full"},
+		InvitedOn:      time.Now().UTC().Format(dropbox.TimestampFormat),
+	}, 30*24*time.Hour, nil)
+	require.NoError(t, err)
+
+	require.Equal(t, v2.Status_ResourceStatus(v2.UserTrait_Status_STATUS_UNSPECIFIED), res.GetStatus().GetStatus())
+	require.Equal(t, "invited", res.GetStatus().GetDetails())
+	require.NotContains(t, res.GetProfile().AsMap(), "invitation_stale")
+}
+
+func TestUserResource_JoinDatesAndEmployeeIDs(t *testing.T) {
+	res, err := userResource(dropbox.Profile{
+		TeamMemberID:          "dbmid:1",
+		Email:                 "alice@example.com",
+		Status:                dropbox.Tag{Tag: "suspended"},
+		MembershipType:        dropbox.Tag{Tag: "full"},
+		JoinedOn:              "2021-05-06T07:08:09Z",
+		SuspendedOn:           "2024-01-02T03:04:05Z",
+		ExternalID:            "E12345",
+		PersistentID:          "saml-alice",
+		AccountType:           dropbox.Tag{Tag: "business"},
+		IsDirectoryRestricted: true,
+	}, 0, nil)
+	require.NoError(t, err)
+
+	trait, err := resourceSdk.GetUserTrait(res)
+	require.NoError(t, err)
+	require.Equal(t, []string{"E12345", "saml-alice"}, trait.GetEmployeeIds())
+	require.Equal(t, mustParseDropboxTime(t, "2021-05-06T07:08:09Z"), trait.GetCreatedAt().AsTime())
+
+	profile := res.GetProfile().AsMap()
+	require.Equal(t, "2021-05-06T07:08:09Z", profile["joined_on"])
+	require.Equal(t, "2024-01-02T03:04:05Z", profile["suspended_on"])
+	require.Equal(t, "E12345", profile["external_id"])
+	require.Equal(t, "saml-alice", profile["persistent_id"])
+	require.Equal(t, "business", profile["account_type"])
+	require.Equal(t, true, profile["is_directory_restricted"])
+}
+
+func TestUserResource_ProfilePhotoIcon(t *testing.T) {
+	res, err := userResource(dropbox.Profile{
+		TeamMemberID:    "dbmid:1",
+		Email:           "alice@example.com",
+		Status:          dropbox.Tag{Tag: "active"},
+		ProfilePhotoURL: "https://dl-web.dropbox.com/account_photo/get/dbaphid%3A1",
+	}, 0, nil)
+	require.NoError(t, err)
+
+	trait, err := resourceSdk.GetUserTrait(res)
+	require.NoError(t, err)
+	require.Equal(t, "dbmid:1", trait.GetIcon().GetId())
+}
+
+// TestUserBuilder_Delete_TreatsMissingUserAsDeleted verifies that a
+// user_not_found error from members/remove is decoded and treated as success,
+// while other errors still fail with their mapped code.
+func TestUserBuilder_Delete_TreatsMissingUserAsDeleted(t *testing.T) {
+	errorTag := "user_not_found"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/2/team/members/remove", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		_, _ = fmt.Fprintf(w, `{"error_summary": "%s/..", "error": {".tag": %q}}`, errorTag, errorTag)
+	}))
+	defer server.Close()
+
+	builder := newUserBuilder(newTestConnector(t, server).client, false, 0, false)
+	userID := &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "dbmid:1"}
+
+	_, err := builder.Delete(context.Background(), userID)
+	require.NoError(t, err)
+
+	errorTag = "remove_last_admin"
+	_, err = builder.Delete(context.Background(), userID)
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+// TestUserBuilder_List_RestartsOnExpiredCursor verifies that when Dropbox
+// rejects a cursor mid-listing, the listing restarts from the first page and
+// members already returned are not returned again.
+func TestUserBuilder_List_RestartsOnExpiredCursor(t *testing.T) {
+	member := func(id string) string {
+		return fmt.Sprintf(`{"profile": {"team_member_id": %q, "email": "%s@example.com", "status": {".tag": "active"}, "membership_type": {".tag": "full"}}}`, id, id)
+	}
+	firstPages := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		var body struct {
+			Cursor string `json:"cursor"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		switch {
+		case r.URL.Path == "/2/team/members/list_v2":
+			firstPages++
+			_, _ = fmt.Fprintf(w, `{"members": [%s, %s], "cursor": "cursor-%d", "has_more": true}`, member("dbmid:1"), member("dbmid:2"), firstPages)
+		case body.Cursor == "cursor-1":
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"error_summary": "invalid_cursor/..", "error": {".tag": "invalid_cursor"}}`))
+		default:
+			require.Equal(t, "cursor-2", body.Cursor)
+			_, _ = fmt.Fprintf(w, `{"members": [%s], "cursor": "cursor-3", "has_more": false}`, member("dbmid:3"))
+		}
+	}))
+	defer server.Close()
+
+	builder := newUserBuilder(newTestConnector(t, server).client, false, 0, false)
+
+	var listed []string
+	token := ""
+	for page := 0; page < 5; page++ {
+		resources, results, err := builder.List(context.Background(), nil, resourceSdk.SyncOpAttrs{PageToken: pagination.Token{Token: token}})
+		require.NoError(t, err)
+		for _, resource := range resources {
+			listed = append(listed, resource.GetId().GetResource())
+		}
+		token = results.NextPageToken
+		if token == "" {
+			break
+		}
+	}
+
+	require.Equal(t, []string{"dbmid:1", "dbmid:2", "dbmid:3"}, listed)
+	require.Equal(t, 2, firstPages)
+}
+
+// TestUserBuilder_List_RestartSkipsReturnedMembersByID verifies that a
+// listing restarted after members were added and removed returns the new
+// members and skips only the ones already returned, wherever they now are,
+// using the IDs recorded in the session store.
+func TestUserBuilder_List_RestartSkipsReturnedMembersByID(t *testing.T) {
+	member := func(id string) string {
+		return fmt.Sprintf(`{"profile": {"team_member_id": %q, "email": "%s@example.com", "status": {".tag": "active"}, "membership_type": {".tag": "full"}}}`, id, id)
+	}
+	firstPages := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		var body struct {
+			Cursor string `json:"cursor"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		switch {
+		case r.URL.Path == "/2/team/members/list_v2" && firstPages == 0:
+			firstPages++
+			_, _ = fmt.Fprintf(w, `{"members": [%s, %s], "cursor": "cursor-1", "has_more": true}`, member("dbmid:1"), member("dbmid:2"))
+		case r.URL.Path == "/2/team/members/list_v2":
+			// dbmid:1 was removed and dbmid:0 added ahead of dbmid:2.
+			firstPages++
+			_, _ = fmt.Fprintf(w, `{"members": [%s, %s], "cursor": "cursor-2", "has_more": true}`, member("dbmid:0"), member("dbmid:2"))
+		case body.Cursor == "cursor-1":
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"error_summary": "reset/..", "error": {".tag": "reset"}}`))
+		default:
+			require.Equal(t, "cursor-2", body.Cursor)
+			_, _ = fmt.Fprintf(w, `{"members": [%s, %s], "cursor": "cursor-3", "has_more": false}`, member("dbmid:1"), member("dbmid:3"))
+		}
+	}))
+	defer server.Close()
+
+	builder := newUserBuilder(newTestConnector(t, server).client, false, 0, false)
+
+	ss := memorySessionStore{}
+	var listed []string
+	token := ""
+	for page := 0; page < 5; page++ {
+		resources, results, err := builder.List(context.Background(), nil, resourceSdk.SyncOpAttrs{PageToken: pagination.Token{Token: token}, Session: ss})
+		require.NoError(t, err)
+		for _, resource := range resources {
+			listed = append(listed, resource.GetId().GetResource())
+		}
+		token = results.NextPageToken
+		if token == "" {
+			break
+		}
+	}
+
+	require.Equal(t, []string{"dbmid:1", "dbmid:2", "dbmid:0", "dbmid:3"}, listed)
+	require.Equal(t, 2, firstPages)
+}